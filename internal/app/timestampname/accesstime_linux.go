@@ -0,0 +1,21 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package timestampname
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileAccessTime returns access time of the file, false if it is not known.
+func fileAccessTime(stat os.FileInfo) (time.Time, bool) {
+	sys, isStat := stat.Sys().(*syscall.Stat_t)
+	if !isStat {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(sys.Atim.Sec), int64(sys.Atim.Nsec)), true
+}
@@ -0,0 +1,16 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package timestampname
+
+import (
+	"os"
+	"time"
+)
+
+// fileAccessTime returns access time of the file, false if it is not known.
+func fileAccessTime(stat os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
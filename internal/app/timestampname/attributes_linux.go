@@ -0,0 +1,43 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux && (386 || amd64 || arm || arm64 || riscv64 || loong64 || s390x)
+
+package timestampname
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// ioctl requests and inode flags from linux/fs.h,
+// requests are declared with long argument but the kernel reads an int:
+const (
+	fsIocGetFlags   = 2<<30 | unsafe.Sizeof(uintptr(0))<<16 | 'f'<<8 | 1
+	fsIocSetFlags   = 1<<30 | unsafe.Sizeof(uintptr(0))<<16 | 'f'<<8 | 2
+	fsImmutableFlag = 0x00000010
+	fsAppendFlag    = 0x00000020
+)
+
+func setFileAttributes(name string, attributes string) error {
+	var flag uint32 = fsImmutableFlag
+	if attributes == attributesAppend {
+		flag = fsAppendFlag
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var flags uint32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), fsIocGetFlags, uintptr(unsafe.Pointer(&flags))); errno != 0 {
+		return errno
+	}
+	flags |= flag
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), fsIocSetFlags, uintptr(unsafe.Pointer(&flags))); errno != 0 {
+		return errno
+	}
+	return nil
+}
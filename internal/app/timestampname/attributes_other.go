@@ -0,0 +1,14 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux || !(386 || amd64 || arm || arm64 || riscv64 || loong64 || s390x)
+
+package timestampname
+
+import (
+	"errors"
+)
+
+func setFileAttributes(name string, attributes string) error {
+	return errors.New("file attributes are not supported on this platform")
+}
@@ -0,0 +1,167 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	tsn "github.com/TheIndifferent/timestampname-go/pkg/timestampname"
+)
+
+// clockTolerance is the difference from GPS time still considered correct,
+// GPS time stamp of the last fix may lag behind the shot.
+const clockTolerance = time.Minute
+
+const unknownCamera = "unknown camera"
+
+// clockSources are timestamp sources recorded by camera clock.
+var clockSources = []string{tsn.SourceOriginal, tsn.SourceDigitized, tsn.SourceModified}
+
+// fromCameraClock reports whether timestamps of the source are recorded by camera clock.
+func fromCameraClock(source string) bool {
+	for _, clockSource := range clockSources {
+		if source == clockSource {
+			return true
+		}
+	}
+	return false
+}
+
+// cameraClock collects offsets of camera clock to GPS time, file by file.
+type cameraClock struct {
+	camera  string
+	offsets []time.Duration
+}
+
+// median returns median offset rounded to seconds.
+func (c *cameraClock) median() time.Duration {
+	var sorted = append([]time.Duration{}, c.offsets...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2].Round(time.Second)
+}
+
+// clockOffset returns difference of the embedded date to Exif GPS time of the file,
+// it includes zone of the camera clock. False if the file lacks either of them.
+func clockOffset(result tsn.Result) (time.Duration, bool) {
+	var gpsTime time.Time
+	for _, candidate := range result.Candidates {
+		if candidate.Source == tsn.SourceExifGps {
+			gpsTime = candidate.Time
+		}
+	}
+	if gpsTime.IsZero() {
+		return 0, false
+	}
+	for _, source := range clockSources {
+		for _, candidate := range result.Candidates {
+			// floating time carries the wall clock in UTC:
+			if candidate.Source == source && candidate.Floating {
+				return candidate.Time.Sub(gpsTime), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// clockName names clock of the camera by make and model, and by serial number if known,
+// as bodies of the same model have clocks of their own.
+func clockName(camera tsn.Camera) string {
+	var name = camera.String()
+	if len(name) == 0 {
+		name = unknownCamera
+	}
+	if len(camera.Serial) > 0 {
+		name += " #" + camera.Serial
+	}
+	return name
+}
+
+// cameraClocks groups clock offsets of the files by camera, sorted by camera name.
+func cameraClocks(files []string) []*cameraClock {
+	var options = extractOptions()
+	options.CollectAll = true
+	var clocks = make(map[string]*cameraClock)
+	for index, file := range files {
+		info("\rProcessing files: %d/%d...", index+1, len(files))
+		// candidates are enough, failure to select a timestamp does not matter:
+		result, _ := tsn.ExtractFileCreationTime(file, options)
+		offset, found := clockOffset(result)
+		if !found {
+			debug("%s has no Exif GPS time or embedded date", file)
+			continue
+		}
+		var camera = clockName(result.Camera)
+		if clocks[camera] == nil {
+			clocks[camera] = &cameraClock{camera: camera}
+		}
+		clocks[camera].offsets = append(clocks[camera].offsets, offset)
+	}
+	info(" done.\n")
+	var sorted []*cameraClock
+	for _, clock := range clocks {
+		sorted = append(sorted, clock)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].camera < sorted[j].camera })
+	return sorted
+}
+
+// expectedClockOffset returns offset to UTC of camera clocks set to -timezone.
+func expectedClockOffset() time.Duration {
+	// -timezone is a fixed zone, any time gives its offset:
+	_, zoneOffset := time.Now().In(cmdArgs.timezone).Zone()
+	return time.Duration(zoneOffset) * time.Second
+}
+
+// formatOffset formats offset as UTC+hh:mm:ss.
+func formatOffset(offset time.Duration) string {
+	var sign = "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	var seconds = int64(offset / time.Second)
+	return fmt.Sprintf("UTC%s%02d:%02d:%02d", sign, seconds/3600, seconds/60%60, seconds%60)
+}
+
+// reportClocks compares camera clocks to Exif GPS time and reports the ones
+// that differ from -timezone, with -shift correcting them.
+func reportClocks(files []string) {
+	var clocks = cameraClocks(files)
+	if len(clocks) == 0 {
+		info("No files with both Exif GPS time and embedded date found.\n")
+		return
+	}
+	var expected = expectedClockOffset()
+	var longestCamera int
+	for _, clock := range clocks {
+		if len(clock.camera) > longestCamera {
+			longestCamera = len(clock.camera)
+		}
+	}
+	info("Camera clocks, expected %s:\n", formatOffset(expected))
+	for _, clock := range clocks {
+		var offset = clock.median()
+		var status = "ok"
+		if drift := offset - expected; drift > clockTolerance || drift < -clockTolerance {
+			status = fmt.Sprintf("off by %v, correct with -shift %v", drift, -drift)
+		}
+		info("    %-*s  %4d files  clock %s  %s\n", longestCamera, clock.camera, len(clock.offsets), formatOffset(offset), status)
+	}
+}
+
+// clocksFiles returns files given on command line, or supported files of the working folder.
+func clocksFiles(files []string) []string {
+	if len(files) > 0 {
+		return files
+	}
+	workDir, err := os.Getwd()
+	Catch(err, "failed to get current working directory")
+	for _, file := range listFiles(workDir) {
+		files = append(files, file.name)
+	}
+	return files
+}
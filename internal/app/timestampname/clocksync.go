@@ -0,0 +1,229 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// clock synchronisation compares shots of every camera to shots of the reference camera,
+// differences are counted in windows, the window with most of them gives the offset:
+const (
+	syncWindow     = time.Minute
+	syncMaxOffset  = 24 * time.Hour
+	syncMinMatches = 3
+)
+
+// clockProposal is the offset proposed for the camera files.
+type clockProposal struct {
+	camera string
+	files  int
+	offset time.Duration
+	// reason explains the offset, or why there is none:
+	reason string
+	found  bool
+}
+
+// syncClocks proposes offsets of camera clocks, asks for confirmation
+// and applies them to timestamps of the files.
+func syncClocks(metadatas []fileMetadata) {
+	var proposals = proposeClockOffsets(metadatas)
+	if len(proposals) == 0 {
+		info("Clock synchronisation needs files of at least two cameras or Exif GPS time.\n")
+		return
+	}
+	var longestCamera int
+	var offsets = make(map[string]time.Duration)
+	for _, proposal := range proposals {
+		if len(proposal.camera) > longestCamera {
+			longestCamera = len(proposal.camera)
+		}
+		if proposal.found && proposal.offset != 0 {
+			offsets[proposal.camera] = proposal.offset
+		}
+	}
+	info("Clock synchronisation:\n")
+	for _, proposal := range proposals {
+		var offset = "-"
+		if proposal.found {
+			offset = proposal.offset.String()
+		}
+		info("    %-*s  %4d files  %10s  %s\n", longestCamera, proposal.camera, proposal.files, offset, proposal.reason)
+	}
+	if len(offsets) == 0 {
+		info("Clocks are in sync.\n")
+		return
+	}
+	if !confirm("Apply clock offsets?") {
+		info("Clock offsets not applied.\n")
+		return
+	}
+	for index := range metadatas {
+		camera, synced := syncedCamera(metadatas[index])
+		if offset, exists := offsets[camera]; synced && exists {
+			debug("shifting %s timestamp %v by %v", metadatas[index].name, metadatas[index].Time, offset)
+			metadatas[index].Time = metadatas[index].Time.Add(offset)
+		}
+	}
+}
+
+// syncedCamera returns camera of the file, false if the file takes no part in synchronisation:
+// its timestamp is not recorded by camera clock, or the camera is unknown without -sync-unknown.
+func syncedCamera(md fileMetadata) (string, bool) {
+	if !fromCameraClock(md.Source) {
+		return "", false
+	}
+	if len(md.Camera.String()) == 0 {
+		return clockName(md.Camera), cmdArgs.syncUnknown
+	}
+	return clockName(md.Camera), true
+}
+
+// proposeClockOffsets proposes offsets for every camera of synchronised files, sorted by camera name.
+// Cameras with Exif GPS time are corrected to -timezone, the reference camera is the one
+// with most GPS time stamps, or with most files if there are none. Other cameras are
+// matched against shots of the reference camera.
+func proposeClockOffsets(metadatas []fileMetadata) []clockProposal {
+	var timesByCamera = make(map[string][]time.Time)
+	var clocks = make(map[string]*cameraClock)
+	for _, md := range metadatas {
+		camera, synced := syncedCamera(md)
+		if !synced {
+			continue
+		}
+		timesByCamera[camera] = append(timesByCamera[camera], md.Time)
+		if offset, found := clockOffset(md.Result); found {
+			if clocks[camera] == nil {
+				clocks[camera] = &cameraClock{camera: camera}
+			}
+			// candidates are not shifted, timestamps of synchronised files are:
+			clocks[camera].offsets = append(clocks[camera].offsets, offset+cmdArgs.shift)
+		}
+	}
+	if len(timesByCamera) < 2 && len(clocks) == 0 {
+		return nil
+	}
+
+	var cameras []string
+	for camera := range timesByCamera {
+		cameras = append(cameras, camera)
+	}
+	sort.Strings(cameras)
+	var reference string
+	for _, camera := range cameras {
+		switch {
+		case len(reference) == 0:
+			reference = camera
+		case clocks[camera] != nil && (clocks[reference] == nil || len(clocks[camera].offsets) > len(clocks[reference].offsets)):
+			reference = camera
+		case clocks[reference] == nil && len(timesByCamera[camera]) > len(timesByCamera[reference]):
+			reference = camera
+		}
+	}
+
+	var expected = expectedClockOffset()
+	var proposals = make(map[string]clockProposal)
+	for camera, clock := range clocks {
+		var offset = expected - clock.median()
+		if offset < clockTolerance && offset > -clockTolerance {
+			offset = 0
+		}
+		proposals[camera] = clockProposal{camera, len(timesByCamera[camera]), offset, fmt.Sprintf("Exif GPS time of %d files", len(clock.offsets)), true}
+	}
+	if _, exists := proposals[reference]; !exists {
+		proposals[reference] = clockProposal{camera: reference, files: len(timesByCamera[reference]), reason: "reference", found: true}
+	}
+	var referenceTimes []time.Time
+	for _, t := range timesByCamera[reference] {
+		referenceTimes = append(referenceTimes, t.Add(proposals[reference].offset))
+	}
+	sort.Slice(referenceTimes, func(i, j int) bool { return referenceTimes[i].Before(referenceTimes[j]) })
+
+	var sorted []clockProposal
+	for _, camera := range cameras {
+		proposal, exists := proposals[camera]
+		if !exists {
+			proposal = clockProposal{camera: camera, files: len(timesByCamera[camera])}
+			offset, matches := matchClockOffset(timesByCamera[camera], referenceTimes)
+			if matches < syncMinMatches {
+				proposal.reason = "no overlap with " + reference
+			} else {
+				if offset < clockTolerance && offset > -clockTolerance {
+					offset = 0
+				}
+				proposal.offset = offset
+				proposal.found = true
+				proposal.reason = fmt.Sprintf("%d shot pairs matching %s", matches, reference)
+			}
+		}
+		sorted = append(sorted, proposal)
+	}
+	return sorted
+}
+
+// matchClockOffset returns offset moving most of the times close to the reference times
+// and the number of time pairs agreeing on it. Reference times must be sorted.
+func matchClockOffset(times []time.Time, reference []time.Time) (time.Duration, int) {
+	// visit calls f for every difference to the reference within the maximal offset:
+	var visit = func(f func(diff time.Duration)) {
+		for _, t := range times {
+			var start = sort.Search(len(reference), func(i int) bool {
+				return !reference[i].Before(t.Add(-syncMaxOffset))
+			})
+			for i := start; i < len(reference) && !reference[i].After(t.Add(syncMaxOffset)); i++ {
+				f(reference[i].Sub(t))
+			}
+		}
+	}
+	var windows = make(map[int64]int)
+	visit(func(diff time.Duration) {
+		windows[syncWindowOf(diff)]++
+	})
+	// neighbour windows are counted together, offsets close to the window border fall into either:
+	var bestWindow int64
+	var bestCount int
+	for window, count := range windows {
+		count += windows[window+1]
+		if count > bestCount || (count == bestCount && window < bestWindow) {
+			bestWindow, bestCount = window, count
+		}
+	}
+	if bestCount == 0 {
+		return 0, 0
+	}
+	var diffs []time.Duration
+	visit(func(diff time.Duration) {
+		if window := syncWindowOf(diff); window == bestWindow || window == bestWindow+1 {
+			diffs = append(diffs, diff)
+		}
+	})
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i] < diffs[j] })
+	return diffs[len(diffs)/2].Round(time.Second), bestCount
+}
+
+// syncWindowOf returns index of the window the difference falls into, rounding down.
+func syncWindowOf(diff time.Duration) int64 {
+	var window = int64(diff / syncWindow)
+	if diff < 0 && diff%syncWindow != 0 {
+		window--
+	}
+	return window
+}
+
+// confirm asks the question on standard input, anything but yes is no.
+func confirm(question string) bool {
+	info("%s [y/N] ", question)
+	var scanner = bufio.NewScanner(os.Stdin)
+	if !scanner.Scan() {
+		info("\n")
+		return false
+	}
+	var answer = strings.ToLower(strings.TrimSpace(scanner.Text()))
+	return answer == "y" || answer == "yes"
+}
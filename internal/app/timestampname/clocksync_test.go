@@ -0,0 +1,65 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"testing"
+	"time"
+
+	tsn "github.com/TheIndifferent/timestampname-go/pkg/timestampname"
+)
+
+func TestProposeClockOffsetsShifted(t *testing.T) {
+	defer func(args commandLineArguments) { cmdArgs = args }(cmdArgs)
+	cmdArgs.timezone = time.UTC
+	cmdArgs.shift = time.Hour
+
+	// camera clock is an hour behind GPS time, -shift already corrects it:
+	var gpsTime = time.Date(2021, 5, 6, 7, 8, 9, 0, time.UTC)
+	var clockTime = gpsTime.Add(-time.Hour)
+	var md = fileMetadata{inputFile: inputFile{name: "a.jpg"}, Result: tsn.Result{
+		Time:   clockTime.Add(cmdArgs.shift),
+		Source: tsn.SourceOriginal,
+		Camera: tsn.Camera{Make: "Canon", Model: "Canon EOS R5"},
+		Candidates: []tsn.Candidate{
+			{Source: tsn.SourceOriginal, Tag: "DateTimeOriginal", Time: clockTime, Floating: true},
+			{Source: tsn.SourceExifGps, Tag: "GPSDateStamp GPSTimeStamp", Time: gpsTime},
+		},
+	}}
+	var proposals = proposeClockOffsets([]fileMetadata{md})
+	if len(proposals) != 1 || !proposals[0].found || proposals[0].offset != 0 {
+		t.Errorf("got proposals %+v, want no further offset", proposals)
+	}
+}
+
+func TestProposeClockOffsetsSerial(t *testing.T) {
+	defer func(args commandLineArguments) { cmdArgs = args }(cmdArgs)
+	cmdArgs.timezone = time.UTC
+	cmdArgs.shift = 0
+
+	var gpsTime = time.Date(2021, 5, 6, 7, 8, 9, 0, time.UTC)
+	// bodies of the same model, the second one is ten minutes behind:
+	var shot = func(serial string, clockOffset time.Duration) fileMetadata {
+		var clockTime = gpsTime.Add(clockOffset)
+		return fileMetadata{inputFile: inputFile{name: serial + ".jpg"}, Result: tsn.Result{
+			Time:   clockTime,
+			Source: tsn.SourceOriginal,
+			Camera: tsn.Camera{Make: "Canon", Model: "Canon EOS R5", Serial: serial},
+			Candidates: []tsn.Candidate{
+				{Source: tsn.SourceOriginal, Tag: "DateTimeOriginal", Time: clockTime, Floating: true},
+				{Source: tsn.SourceExifGps, Tag: "GPSDateStamp GPSTimeStamp", Time: gpsTime},
+			},
+		}}
+	}
+	var proposals = proposeClockOffsets([]fileMetadata{shot("0001", 0), shot("0002", -10*time.Minute)})
+	if len(proposals) != 2 {
+		t.Fatalf("got proposals %+v, want one per body", proposals)
+	}
+	if proposals[0].camera != "Canon EOS R5 #0001" || proposals[0].offset != 0 {
+		t.Errorf("got proposal %+v for the first body", proposals[0])
+	}
+	if proposals[1].camera != "Canon EOS R5 #0002" || proposals[1].offset != 10*time.Minute {
+		t.Errorf("got proposal %+v for the second body", proposals[1])
+	}
+}
@@ -0,0 +1,217 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"bufio"
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// configuration files, the one in working directory overrides the user one,
+// profile tables override top level keys of both:
+const (
+	userConfigFile   = "timestampname/config.toml"
+	folderConfigFile = ".timestampname.toml"
+	profilesTable    = "profiles"
+)
+
+// userConfigPath returns path of the user configuration file, empty if the user has no configuration folder.
+func userConfigPath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, userConfigFile)
+}
+
+// configFiles returns existing configuration files in order of application.
+func configFiles() []string {
+	var files []string
+	if userConfig := userConfigPath(); len(userConfig) > 0 {
+		files = append(files, userConfig)
+	}
+	files = append(files, folderConfigFile)
+	var existing []string
+	for _, file := range files {
+		if _, err := os.Stat(file); err == nil {
+			existing = append(existing, file)
+		}
+	}
+	return existing
+}
+
+// applyConfig sets flags from top level keys of configuration files and then from the profile tables,
+// flags given on the command line are left as they are.
+func applyConfig(profile string) {
+	var explicit = make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	var files = configFiles()
+	var tablesByFile = make([]map[string]map[string]string, len(files))
+	for index, file := range files {
+		debug("reading configuration: %s", file)
+		tablesByFile[index] = parseToml(file)
+		setFlags(file, tablesByFile[index][""], explicit)
+	}
+	var profileFound bool
+	for index, file := range files {
+		if values, exists := tablesByFile[index][profilesTable+"."+profile]; len(profile) > 0 && exists {
+			profileFound = true
+			setFlags(file, values, explicit)
+		}
+	}
+	if len(profile) > 0 && !profileFound {
+		RaiseFmt("profile not found in configuration: %s", profile)
+	}
+}
+
+func setFlags(file string, values map[string]string, explicit map[string]bool) {
+	for name, value := range values {
+		if name == "profile" || flag.Lookup(name) == nil {
+			RaiseFmtFile(file, "unknown option: %s", name)
+		}
+		if explicit[name] {
+			debug("option %s given on command line, ignoring configured value: %s", name, value)
+			continue
+		}
+		CatchFile(flag.Set(name, value), file, "invalid value of option "+name)
+	}
+}
+
+// parseToml parses subset of TOML: tables, comments and key/value pairs of strings,
+// booleans, numbers and arrays of those, arrays become comma separated values.
+// Values are keyed by dotted table name, top level keys by empty name.
+func parseToml(file string) map[string]map[string]string {
+	in, err := os.Open(file)
+	CatchFile(err, file, "failed to open configuration")
+	defer in.Close()
+
+	var tables = map[string]map[string]string{"": {}}
+	var table = ""
+	var scanner = bufio.NewScanner(in)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		var line = strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' {
+			var end = strings.IndexByte(line, ']')
+			if end < 0 || strings.HasPrefix(line, "[[") || !tomlComment(line[end+1:]) {
+				RaiseFmtFile(file, "line %d: invalid table header", lineNumber)
+			}
+			table = strings.TrimSpace(line[1:end])
+			if len(table) == 0 {
+				RaiseFmtFile(file, "line %d: empty table name", lineNumber)
+			}
+			if _, exists := tables[table]; exists {
+				RaiseFmtFile(file, "line %d: table defined twice: %s", lineNumber, table)
+			}
+			tables[table] = make(map[string]string)
+			continue
+		}
+		var separator = strings.IndexByte(line, '=')
+		if separator < 0 {
+			RaiseFmtFile(file, "line %d: key = value expected", lineNumber)
+		}
+		var key = strings.Trim(strings.TrimSpace(line[:separator]), "\"")
+		if len(key) == 0 {
+			RaiseFmtFile(file, "line %d: empty key", lineNumber)
+		}
+		value, ok := tomlValue(strings.TrimSpace(line[separator+1:]))
+		if !ok {
+			RaiseFmtFile(file, "line %d: invalid value of %s", lineNumber, key)
+		}
+		if _, exists := tables[table][key]; exists {
+			RaiseFmtFile(file, "line %d: key defined twice: %s", lineNumber, key)
+		}
+		tables[table][key] = value
+	}
+	CatchFile(scanner.Err(), file, "failed to read configuration")
+	return tables
+}
+
+// tomlValue parses value with optional trailing comment.
+func tomlValue(text string) (string, bool) {
+	if strings.HasPrefix(text, "[") {
+		var values []string
+		var rest = strings.TrimSpace(text[1:])
+		for !strings.HasPrefix(rest, "]") {
+			value, remaining, ok := tomlScalar(rest)
+			if !ok {
+				return "", false
+			}
+			values = append(values, value)
+			rest = strings.TrimSpace(remaining)
+			if strings.HasPrefix(rest, ",") {
+				rest = strings.TrimSpace(rest[1:])
+			} else if !strings.HasPrefix(rest, "]") {
+				return "", false
+			}
+		}
+		return strings.Join(values, ","), tomlComment(rest[1:])
+	}
+	value, rest, ok := tomlScalar(text)
+	return value, ok && tomlComment(rest)
+}
+
+// tomlScalar parses string, boolean or number at the start of the text, returns the rest.
+func tomlScalar(text string) (string, string, bool) {
+	switch {
+	case strings.HasPrefix(text, "'"):
+		var end = strings.IndexByte(text[1:], '\'')
+		if end < 0 {
+			return "", "", false
+		}
+		return text[1 : end+1], text[end+2:], true
+	case strings.HasPrefix(text, "\""):
+		var sb strings.Builder
+		for i := 1; i < len(text); i++ {
+			switch text[i] {
+			case '"':
+				return sb.String(), text[i+1:], true
+			case '\\':
+				i++
+				if i == len(text) {
+					return "", "", false
+				}
+				switch text[i] {
+				case '"', '\\':
+					sb.WriteByte(text[i])
+				case 'n':
+					sb.WriteByte('\n')
+				case 't':
+					sb.WriteByte('\t')
+				default:
+					return "", "", false
+				}
+			default:
+				sb.WriteByte(text[i])
+			}
+		}
+		return "", "", false
+	default:
+		var end = strings.IndexAny(text, " \t,]#")
+		if end < 0 {
+			end = len(text)
+		}
+		var value = text[:end]
+		if value != "true" && value != "false" {
+			if _, err := strconv.ParseFloat(strings.ReplaceAll(value, "_", ""), 64); err != nil {
+				return "", "", false
+			}
+		}
+		return value, text[end:], true
+	}
+}
+
+// tomlComment reports whether the text is empty or a comment.
+func tomlComment(text string) bool {
+	text = strings.TrimSpace(text)
+	return len(text) == 0 || text[0] == '#'
+}
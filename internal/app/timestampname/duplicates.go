@@ -0,0 +1,168 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// duplicate handling modes:
+const (
+	duplicatesOff    = "off"
+	duplicatesReport = "report"
+	duplicatesSkip   = "skip"
+	duplicatesMove   = "move"
+)
+
+// hashing modes, partial hashes only the beginning and the end of files of the same size:
+const (
+	hashFull    = "full"
+	hashPartial = "partial"
+)
+
+const (
+	duplicatesFolder  = "duplicates"
+	partialHashLength = 64 * 1024
+)
+
+type duplicateFile struct {
+	name     string
+	original string
+}
+
+// duplicateDetector remembers content hashes of files seen so far,
+// partial hash of different files may match, so several files are kept per hash.
+type duplicateDetector struct {
+	mode   string
+	hash   string
+	byHash map[string][]string
+	found  []duplicateFile
+}
+
+func newDuplicateDetector(mode string, hash string) *duplicateDetector {
+	if mode == duplicatesOff {
+		return nil
+	}
+	return &duplicateDetector{mode: mode, hash: hash, byHash: make(map[string][]string)}
+}
+
+// check returns name of the first file with the same content, empty if there is none.
+func (d *duplicateDetector) check(name string) string {
+	var key = contentHash(name, d.hash)
+	for _, original := range d.byHash[key] {
+		// duplicates are byte-identical, partial hash is confirmed by comparing content:
+		if d.hash == hashPartial && !sameContent(name, original) {
+			debug("%s matches partial hash of %s, content differs", name, original)
+			continue
+		}
+		debug("%s duplicates %s", name, original)
+		d.found = append(d.found, duplicateFile{name, original})
+		return original
+	}
+	d.byHash[key] = append(d.byHash[key], name)
+	return ""
+}
+
+// renamed updates names of remembered files after renaming, watch mode compares to them later.
+func (d *duplicateDetector) renamed(targets map[string]string) {
+	for key, names := range d.byHash {
+		for index, name := range names {
+			if target, exists := targets[name]; exists {
+				d.byHash[key][index] = target
+			}
+		}
+	}
+}
+
+// sameContent compares files byte by byte.
+func sameContent(name string, other string) bool {
+	in, err := os.Open(name)
+	CatchFile(err, name, "failed to open for comparing")
+	defer in.Close()
+	otherIn, err := os.Open(other)
+	CatchFile(err, other, "failed to open for comparing")
+	defer otherIn.Close()
+
+	var buffer = make([]byte, partialHashLength)
+	var otherBuffer = make([]byte, partialHashLength)
+	for {
+		n, err := io.ReadFull(in, buffer)
+		if err != io.ErrUnexpectedEOF && err != io.EOF {
+			CatchFile(err, name, "failed to read for comparing")
+		}
+		otherN, otherErr := io.ReadFull(otherIn, otherBuffer)
+		if otherErr != io.ErrUnexpectedEOF && otherErr != io.EOF {
+			CatchFile(otherErr, other, "failed to read for comparing")
+		}
+		if !bytes.Equal(buffer[:n], otherBuffer[:otherN]) {
+			return false
+		}
+		if n < len(buffer) {
+			return otherN < len(otherBuffer)
+		}
+	}
+}
+
+func contentHash(name string, mode string) string {
+	in, err := os.Open(name)
+	CatchFile(err, name, "failed to open for hashing")
+	defer in.Close()
+	stat, err := in.Stat()
+	CatchFile(err, name, "failed to stat for hashing")
+
+	var hash = sha256.New()
+	if mode == hashFull || stat.Size() <= 2*partialHashLength {
+		_, err = io.Copy(hash, in)
+		CatchFile(err, name, "failed to hash")
+	} else {
+		_, err = io.Copy(hash, io.NewSectionReader(in, 0, partialHashLength))
+		CatchFile(err, name, "failed to hash")
+		_, err = io.Copy(hash, io.NewSectionReader(in, stat.Size()-partialHashLength, partialHashLength))
+		CatchFile(err, name, "failed to hash")
+	}
+	return fmt.Sprintf("%d:%x", stat.Size(), hash.Sum(nil))
+}
+
+func printDuplicates(duplicates []duplicateFile) {
+	if len(duplicates) == 0 {
+		return
+	}
+	info("Duplicates:\n")
+	for _, duplicate := range duplicates {
+		info("    %s    ==    %s\n", duplicate.name, duplicate.original)
+	}
+}
+
+// moveDuplicates moves duplicates into the duplicates folder keeping their names.
+func moveDuplicates(duplicates []duplicateFile, dryRun bool) {
+	if len(duplicates) == 0 {
+		return
+	}
+	if !dryRun {
+		mkdirErr := os.MkdirAll(duplicatesFolder, 0755)
+		CatchFile(mkdirErr, duplicatesFolder, "failed to create folder")
+	}
+	for index, duplicate := range duplicates {
+		info("\rMoving duplicates: %d/%d", index+1, len(duplicates))
+		var target = filepath.Join(duplicatesFolder, duplicate.name)
+		if fileExists(target) {
+			Raise(target, "exists on file system")
+		}
+		if !dryRun {
+			err := tryFile(func() {
+				renameErr := os.Rename(duplicate.name, target)
+				CatchFile(renameErr, duplicate.name, "move duplicate")
+			})
+			if err != nil {
+				report.failed(duplicate.name, err)
+			}
+		}
+	}
+	info(" done.\n")
+}
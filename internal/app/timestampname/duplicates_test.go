@@ -0,0 +1,37 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDuplicateDetectorPartialHash(t *testing.T) {
+	var dir = t.TempDir()
+	var write = func(name string, middle byte) string {
+		var content = make([]byte, 3*partialHashLength)
+		content[len(content)/2] = middle
+		var path = filepath.Join(dir, name)
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	var original = write("a.jpg", 1)
+	var copied = write("b.jpg", 1)
+	var different = write("c.jpg", 2)
+	for _, mode := range []string{duplicatesReport, duplicatesSkip, duplicatesMove} {
+		var duplicates = newDuplicateDetector(mode, hashPartial)
+		duplicates.check(original)
+		// partial hashes of all three match, only the copy has the same content:
+		if found := duplicates.check(different); found != "" {
+			t.Errorf("%s: file with different content reported as duplicate of %s", mode, found)
+		}
+		if found := duplicates.check(copied); found != original {
+			t.Errorf("%s: got duplicate of %q, want %q", mode, found, original)
+		}
+	}
+}
@@ -7,22 +7,42 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+
+	tsn "github.com/TheIndifferent/timestampname-go/pkg/timestampname"
 )
 
-func _raise(file string, descriptor string, err error) {
+type failure struct {
+	file       string
+	descriptor string
+	err        error
+}
+
+func (f *failure) Error() string {
 	var sb strings.Builder
 	sb.WriteString("Failure:")
-	if len(file) > 0 {
+	if len(f.file) > 0 {
 		sb.WriteString("\n\tFile:       ")
-		sb.WriteString(file)
+		sb.WriteString(f.file)
 	}
 	sb.WriteString("\n\tDescriptor: ")
-	sb.WriteString(descriptor)
-	if err != nil {
+	sb.WriteString(f.descriptor)
+	if f.err != nil {
 		sb.WriteString("\n\tError:      ")
-		sb.WriteString(err.Error())
+		sb.WriteString(f.err.Error())
+	}
+	return sb.String()
+}
+
+// summary is a single line description without the file name.
+func (f *failure) summary() string {
+	if f.err != nil {
+		return f.descriptor + ": " + f.err.Error()
 	}
-	panic(errors.New(sb.String()))
+	return f.descriptor
+}
+
+func _raise(file string, descriptor string, err error) {
+	panic(&failure{file, descriptor, err})
 }
 
 func Raise(file string, descriptor string) {
@@ -52,3 +72,15 @@ func CatchFile(err error, file string, descriptor string) {
 		RaiseErr(file, descriptor, err)
 	}
 }
+
+// CatchLibrary raises failure returned by the library, keeping its file and descriptor.
+func CatchLibrary(err error) {
+	if err == nil {
+		return
+	}
+	var libraryErr *tsn.Error
+	if errors.As(err, &libraryErr) {
+		RaiseErr(libraryErr.File, libraryErr.Descriptor, libraryErr.Err)
+	}
+	RaiseErr("", "unexpected failure", err)
+}
@@ -5,28 +5,14 @@ package timestampname
 
 import (
 	"io/ioutil"
-	"path/filepath"
-	"strings"
+
+	tsn "github.com/TheIndifferent/timestampname-go/pkg/timestampname"
 )
 
 type inputFile struct {
 	name string
-	ext  string
-}
-
-func createSupportedFilesMap() map[string]bool {
-	sf := make(map[string]bool)
-	sf[".dng"] = true
-	sf[".nef"] = true
-	sf[".jpg"] = true
-	sf[".jpeg"] = true
-	sf[".mp4"] = true
-	sf[".cr3"] = true
-	return sf
 }
 
-var supportedFiles = createSupportedFilesMap()
-
 func listFiles(targetFolder string) []inputFile {
 	files, err := ioutil.ReadDir(targetFolder)
 	Catch(err, "cannot read current folder")
@@ -37,12 +23,12 @@ func listFiles(targetFolder string) []inputFile {
 		if file.IsDir() {
 			continue
 		}
-		var ext = strings.ToLower(filepath.Ext(file.Name()))
-		if !supportedFiles[ext] {
+		if !tsn.Supported(file.Name()) {
+			report.skip(file.Name(), "unsupported file type")
 			continue
 		}
 
-		var input = inputFile{name: file.Name(), ext: ext}
+		var input = inputFile{name: file.Name()}
 		inputFiles = append(inputFiles, input)
 	}
 	return inputFiles
@@ -0,0 +1,135 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	tsn "github.com/TheIndifferent/timestampname-go/pkg/timestampname"
+)
+
+type inspectCandidate struct {
+	Source   string `json:"source"`
+	Tag      string `json:"tag"`
+	Time     string `json:"time"`
+	Floating bool   `json:"floating"`
+	Selected bool   `json:"selected"`
+}
+
+type inspectReport struct {
+	File           string             `json:"file"`
+	Extractor      string             `json:"extractor,omitempty"`
+	Make           string             `json:"make,omitempty"`
+	Model          string             `json:"model,omitempty"`
+	Serial         string             `json:"serial,omitempty"`
+	ExtractorError string             `json:"extractorError,omitempty"`
+	Candidates     []inspectCandidate `json:"candidates"`
+	Timestamp      string             `json:"timestamp,omitempty"`
+	Source         string             `json:"source,omitempty"`
+	Tag            string             `json:"tag,omitempty"`
+	Reason         string             `json:"reason,omitempty"`
+	Error          string             `json:"error,omitempty"`
+}
+
+func newInspectReport(file string, result tsn.Result, err error) inspectReport {
+	var report = inspectReport{
+		File:       file,
+		Extractor:  result.Extractor,
+		Make:       result.Camera.Make,
+		Model:      result.Camera.Model,
+		Serial:     result.Camera.Serial,
+		Candidates: []inspectCandidate{},
+	}
+	if result.ExtractorErr != nil {
+		report.ExtractorError = result.ExtractorErr.Error()
+	}
+	for _, candidate := range result.Candidates {
+		var candidateTime = candidate.In(cmdArgs.timezone)
+		report.Candidates = append(report.Candidates, inspectCandidate{
+			Source:   candidate.Source,
+			Tag:      candidate.Tag,
+			Time:     candidateTime.Format(time.RFC3339Nano),
+			Floating: candidate.Floating,
+			Selected: err == nil && candidate.Source == result.Source && candidate.Tag == result.Tag && candidateTime.Equal(result.Time),
+		})
+	}
+	if err != nil {
+		report.Error = err.Error()
+	} else {
+		report.Timestamp = result.Time.Format(tsn.TimestampLayout)
+		report.Source = result.Source
+		report.Tag = result.Tag
+		report.Reason = result.Reason
+	}
+	return report
+}
+
+func printInspectReport(report inspectReport) {
+	if len(report.Extractor) > 0 {
+		info("%s (%s)\n", report.File, report.Extractor)
+	} else {
+		info("%s\n", report.File)
+	}
+	if camera := (tsn.Camera{Make: report.Make, Model: report.Model}).String(); len(camera) > 0 {
+		if len(report.Serial) > 0 {
+			camera += ", serial " + report.Serial
+		}
+		info("    camera: %s\n", camera)
+	}
+	var longestTag int
+	for _, candidate := range report.Candidates {
+		if len(candidate.Tag) > longestTag {
+			longestTag = len(candidate.Tag)
+		}
+	}
+	for _, candidate := range report.Candidates {
+		var marker = "  "
+		if candidate.Selected {
+			marker = "=>"
+		}
+		var floating string
+		if candidate.Floating {
+			floating = " (local)"
+		}
+		info("    %s %-9s  %-*s  %s%s\n", marker, candidate.Source, longestTag, candidate.Tag, candidate.Time, floating)
+	}
+	if len(report.ExtractorError) > 0 {
+		info("    extractor error: %s\n", report.ExtractorError)
+	}
+	if len(report.Error) > 0 {
+		info("    no timestamp selected: %s\n", report.Error)
+	} else {
+		info("    selected %s: %s\n", report.Timestamp, report.Reason)
+	}
+}
+
+// inspectFiles prints every timestamp candidate found in the files and the selected one.
+func inspectFiles(files []string, jsonOutput bool) {
+	var options = extractOptions()
+	options.CollectAll = true
+	var reports = []inspectReport{}
+	var failed int
+	for _, file := range files {
+		result, err := tsn.ExtractFileCreationTime(file, options)
+		if err != nil {
+			failed++
+		}
+		var report = newInspectReport(file, result, err)
+		if jsonOutput {
+			reports = append(reports, report)
+		} else {
+			printInspectReport(report)
+		}
+	}
+	if jsonOutput {
+		var encoder = json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		Catch(encoder.Encode(reports), "failed to write JSON")
+	}
+	if failed > 0 {
+		RaiseFmt("no timestamp selected for %d of %d files", failed, len(files))
+	}
+}
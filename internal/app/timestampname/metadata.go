@@ -4,48 +4,25 @@
 package timestampname
 
 import (
-	"os"
+	tsn "github.com/TheIndifferent/timestampname-go/pkg/timestampname"
 )
 
 type fileMetadata struct {
 	inputFile
-	metadataCreationTimestamp string
+	tsn.Result
 }
 
-func extractMetadataCreationTimestamp(file inputFile) string {
-
-	openFile, openErr := os.Open(file.name)
-	CatchFile(openErr, file.name, "failed to open")
-	defer func() {
-		closeErr := openFile.Close()
-		CatchFile(closeErr, file.name, "failed to close")
-	}()
-
-	var in = newFileReader(openFile, file.name)
-
-	switch file.ext {
-	case ".mp4":
-		return mp4ExtractMetadataCreationTimestamp(in)
-	case ".dng":
-		return tiffExtractMetadataCreationTimestamp(in)
-	case ".nef":
-		return tiffExtractMetadataCreationTimestamp(in)
-	case ".jpg":
-		return jpegExtractMetadataCreationTimestamp(in)
-	case ".jpeg":
-		return jpegExtractMetadataCreationTimestamp(in)
-	case ".cr3":
-		return cr3ExtractMetadataCreationTimestamp(in)
-	default:
-		Raise(file.name, "unsupported file format")
-		return ""
-	}
+func extractOptions() tsn.Options {
+	return tsn.Options{Zone: cmdArgs.timezone, Precedence: cmdArgs.precedence}
 }
 
 func fileMetadataCreationTimestamp(file inputFile) fileMetadata {
-	var metadataCreationTimestamp = extractMetadataCreationTimestamp(file)
-	var metadata = fileMetadata{
-		inputFile:                 file,
-		metadataCreationTimestamp: metadataCreationTimestamp}
-	return metadata
+	result, err := tsn.ExtractFileCreationTime(file.name, extractOptions())
+	CatchLibrary(err)
+	// shift corrects camera clock, GPS time and fallbacks are left alone:
+	if cmdArgs.shift != 0 && fromCameraClock(result.Source) {
+		debug("shifting %s timestamp %v by %v", file.name, result.Time, cmdArgs.shift)
+		result.Time = result.Time.Add(cmdArgs.shift)
+	}
+	return fileMetadata{inputFile: file, Result: result}
 }
@@ -0,0 +1,72 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"io"
+	"os"
+
+	tsn "github.com/TheIndifferent/timestampname-go/pkg/timestampname"
+)
+
+const backupSuffix = ".orig"
+
+// writeMetadata shifts embedded timestamps of the file by -shift,
+// the rename already happened so failures are warnings.
+func writeMetadata(name string, dryRun bool) {
+	var flags = os.O_RDWR
+	if dryRun {
+		flags = os.O_RDONLY
+	}
+	file, err := os.OpenFile(name, flags, 0)
+	if err != nil {
+		warn(name, "writing metadata failed: %v", err)
+		return
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		warn(name, "writing metadata failed: %v", err)
+		return
+	}
+	patches, err := tsn.ShiftTimestamps(file, stat.Size(), name, cmdArgs.shift)
+	if err != nil {
+		warn(name, "writing metadata failed: %v", err)
+		return
+	}
+	for _, patch := range patches {
+		debug("%s: %s %v => %v at offset %d", name, patch.Tag, patch.Old, patch.New, patch.Offset)
+	}
+	if dryRun {
+		info("\n    %s: %d timestamps would be shifted by %v\n", name, len(patches), cmdArgs.shift)
+		return
+	}
+	if cmdArgs.backup {
+		if err = copyFile(name, name+backupSuffix); err != nil {
+			warn(name, "backup failed, metadata not written: %v", err)
+			return
+		}
+	}
+	if err = tsn.WritePatches(file, patches); err != nil {
+		warn(name, "writing metadata failed: %v", err)
+	}
+}
+
+// copyFile copies the file, target must not exist.
+func copyFile(from string, to string) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,55 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"os"
+	"strconv"
+)
+
+// permission modes besides octal file mode:
+const (
+	// chmodKeep leaves permission bits as they are.
+	chmodKeep = "keep"
+	// chmodNone changes neither permission bits nor file attributes.
+	chmodNone = "none"
+)
+
+// file attributes applied after rename where supported:
+const (
+	attributesNone      = ""
+	attributesImmutable = "immutable"
+	attributesAppend    = "append"
+)
+
+// parseChmod returns file mode of the octal value, false for keep and none.
+func parseChmod(value string) (os.FileMode, bool) {
+	if value == chmodKeep || value == chmodNone {
+		return 0, false
+	}
+	mode, err := strconv.ParseUint(value, 8, 32)
+	Catch(err, "invalid -chmod, expected octal mode, keep or none")
+	if mode > 0777 {
+		RaiseFmt("invalid -chmod, permission bits expected: %s", value)
+	}
+	return os.FileMode(mode), true
+}
+
+// applyPermissions changes mode and attributes of the renamed file,
+// the rename already happened so failures are warnings.
+func applyPermissions(name string) {
+	if cmdArgs.chmod == chmodNone {
+		return
+	}
+	if mode, change := parseChmod(cmdArgs.chmod); change {
+		if err := os.Chmod(name, mode); err != nil {
+			warn(name, "chmod failed: %v", err)
+		}
+	}
+	if cmdArgs.attributes != attributesNone {
+		if err := setFileAttributes(name, cmdArgs.attributes); err != nil {
+			warn(name, "setting %s attribute failed: %v", cmdArgs.attributes, err)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tsn "github.com/TheIndifferent/timestampname-go/pkg/timestampname"
+)
+
+// planEntry is a rename operation written for review, timestamp carries its zone offset,
+// size and modification time let applying verify the file did not change since planning.
+type planEntry struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Timestamp string `json:"timestamp"`
+	Source    string `json:"source"`
+	Tag       string `json:"tag"`
+	Size      int64  `json:"size"`
+	Mtime     string `json:"mtime"`
+}
+
+var planCsvHeader = []string{"from", "to", "timestamp", "source", "tag", "size", "mtime"}
+
+func isCsvPlan(file string) bool {
+	return strings.ToLower(filepath.Ext(file)) == ".csv"
+}
+
+func writePlan(file string, operations []tsn.Rename, metadatas []fileMetadata) {
+	var metadataByName = make(map[string]fileMetadata)
+	for _, md := range metadatas {
+		metadataByName[md.name] = md
+	}
+	var entries = make([]planEntry, len(operations))
+	for index, operation := range operations {
+		var md = metadataByName[operation.From]
+		stat, err := os.Stat(operation.From)
+		CatchFile(err, operation.From, "failed to stat")
+		entries[index] = planEntry{
+			From:      operation.From,
+			To:        operation.To,
+			Timestamp: operation.Time.Format(time.RFC3339),
+			Source:    md.Source,
+			Tag:       md.Tag,
+			Size:      stat.Size(),
+			Mtime:     stat.ModTime().Format(time.RFC3339Nano),
+		}
+	}
+
+	out, err := os.Create(file)
+	CatchFile(err, file, "failed to create plan")
+	defer func() {
+		closeErr := out.Close()
+		CatchFile(closeErr, file, "failed to close plan")
+	}()
+	if isCsvPlan(file) {
+		var writer = csv.NewWriter(out)
+		CatchFile(writer.Write(planCsvHeader), file, "failed to write plan")
+		for _, entry := range entries {
+			err = writer.Write([]string{
+				entry.From,
+				entry.To,
+				entry.Timestamp,
+				entry.Source,
+				entry.Tag,
+				strconv.FormatInt(entry.Size, 10),
+				entry.Mtime})
+			CatchFile(err, file, "failed to write plan")
+		}
+		writer.Flush()
+		CatchFile(writer.Error(), file, "failed to write plan")
+	} else {
+		var encoder = json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		CatchFile(encoder.Encode(entries), file, "failed to write plan")
+	}
+}
+
+func readPlan(file string) []planEntry {
+	in, err := os.Open(file)
+	CatchFile(err, file, "failed to open plan")
+	defer in.Close()
+
+	var entries []planEntry
+	if isCsvPlan(file) {
+		records, err := csv.NewReader(in).ReadAll()
+		CatchFile(err, file, "failed to read plan")
+		if len(records) == 0 || strings.Join(records[0], ",") != strings.Join(planCsvHeader, ",") {
+			RaiseFmtFile(file, "plan must start with header: %s", strings.Join(planCsvHeader, ","))
+		}
+		for _, record := range records[1:] {
+			size, err := strconv.ParseInt(record[5], 10, 64)
+			CatchFile(err, file, "invalid size of "+record[0])
+			entries = append(entries, planEntry{record[0], record[1], record[2], record[3], record[4], size, record[6]})
+		}
+	} else {
+		CatchFile(json.NewDecoder(in).Decode(&entries), file, "failed to read plan")
+	}
+	return entries
+}
+
+// validatePlan checks that files did not change since planning
+// and that possibly hand edited targets do not collide.
+func validatePlan(file string, entries []planEntry) ([]tsn.Rename, int) {
+	var operations []tsn.Rename
+	var sources = make(map[string]bool)
+	var targets = make(map[string]bool)
+	var longestSourceName int
+	for _, entry := range entries {
+		if len(entry.To) == 0 || entry.To == entry.From {
+			continue
+		}
+		if entry.To != filepath.Base(entry.To) || entry.To == "." || entry.To == ".." {
+			RaiseFmtFile(file, "target of %s must be a file name in the same folder: %s", entry.From, entry.To)
+		}
+		if sources[entry.From] {
+			Raise(entry.From, "listed twice in plan")
+		}
+		sources[entry.From] = true
+		if targets[entry.To] {
+			Raise(entry.To, "duplicate rename")
+		}
+		targets[entry.To] = true
+
+		stat, err := os.Stat(entry.From)
+		CatchFile(err, entry.From, "failed to stat planned file")
+		if stat.Size() != entry.Size || stat.ModTime().Format(time.RFC3339Nano) != entry.Mtime {
+			Raise(entry.From, "changed since planning")
+		}
+		timestamp, err := time.Parse(time.RFC3339, entry.Timestamp)
+		CatchFile(err, file, "invalid timestamp of "+entry.From)
+		operations = append(operations, tsn.Rename{From: entry.From, To: entry.To, Source: entry.Source, Time: timestamp})
+		if len(entry.From) > longestSourceName {
+			longestSourceName = len(entry.From)
+		}
+	}
+	return operations, longestSourceName
+}
@@ -0,0 +1,45 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	tsn "github.com/TheIndifferent/timestampname-go/pkg/timestampname"
+)
+
+// precedence of XMP and Exif GPS timestamps relative to embedded dates:
+const (
+	precedenceFallback = "fallback"
+	precedencePrefer   = "prefer"
+	precedenceIgnore   = "ignore"
+)
+
+// defaultPrecedence keeps behaviour of the tool before precedence was configurable:
+// GPS time first, then the earliest of embedded dates,
+// then XMP and file name and modification time fallbacks if enabled.
+// Exif GPS time is used only if asked for.
+func defaultPrecedence(xmp string, exifGps string, fromFilename bool, fromMtime bool) [][]string {
+	var precedence [][]string
+	if xmp == precedencePrefer {
+		precedence = append(precedence, []string{tsn.SourceXmp})
+	}
+	if exifGps == precedencePrefer {
+		precedence = append(precedence, []string{tsn.SourceExifGps})
+	}
+	precedence = append(precedence,
+		[]string{tsn.SourceGps},
+		[]string{tsn.SourceOriginal, tsn.SourceDigitized, tsn.SourceModified})
+	if exifGps == precedenceFallback {
+		precedence = append(precedence, []string{tsn.SourceExifGps})
+	}
+	if xmp == precedenceFallback {
+		precedence = append(precedence, []string{tsn.SourceXmp})
+	}
+	if fromFilename {
+		precedence = append(precedence, []string{tsn.SourceFilename})
+	}
+	if fromMtime {
+		precedence = append(precedence, []string{tsn.SourceMtime})
+	}
+	return precedence
+}
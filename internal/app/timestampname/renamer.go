@@ -4,72 +4,178 @@
 package timestampname
 
 import (
-	"fmt"
-	"sort"
+	"encoding/csv"
+	"os"
+	"time"
+
+	tsn "github.com/TheIndifferent/timestampname-go/pkg/timestampname"
 )
 
-type renameOperation struct {
-	from string
-	to   string
+func prepareRenameOperations(files []fileMetadata, options tsn.PlanOptions) ([]tsn.Rename, int) {
+	var planFiles = make([]tsn.File, len(files))
+	var longestSourceName int
+	for index, md := range files {
+		planFiles[index] = tsn.File{Name: md.name, Time: md.Time, Source: md.Source, Camera: md.Camera.String()}
+		// choosing longest source file name for next operation:
+		sourceNameLength := len(md.name)
+		if sourceNameLength > longestSourceName {
+			longestSourceName = sourceNameLength
+		}
+	}
+
+	operations, err := tsn.Plan(planFiles, options)
+	CatchLibrary(err)
+	return operations, longestSourceName
 }
 
-func targetFileNameFormat(numberOfFiles int, noPrefix bool) string {
-	if noPrefix {
-		return "%[2]s%[3]s"
+// pendingOperations leaves out files already having their target name.
+func pendingOperations(operations []tsn.Rename) []tsn.Rename {
+	var pending []tsn.Rename
+	var named int
+	for _, operation := range operations {
+		if operation.Skipped {
+			info("Skipping %s, target name collision.\n", operation.From)
+			report.skip(operation.From, "target name collision")
+			continue
+		}
+		if operation.From == operation.To {
+			debug("already named: %s", operation.From)
+			report.skip(operation.From, "already named")
+			named++
+			continue
+		}
+		pending = append(pending, operation)
 	}
-	if numberOfFiles < 10 {
-		return "%d-%s%s"
+	if named > 0 {
+		info("%d files already named.\n", named)
 	}
-	if numberOfFiles < 100 {
-		return "%02d-%s%s"
+	return pending
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+func renameSources(operations []tsn.Rename) map[string]bool {
+	var sources = make(map[string]bool)
+	for _, operation := range operations {
+		if operation.From != operation.To {
+			sources[operation.From] = true
+		}
+	}
+	return sources
+}
+
+// stageChainedRenames moves files aside to temporary names when a target is the source of another rename,
+// as happens when counters shift on a re-run. Returned operations rename from the temporary names.
+func stageChainedRenames(operations []tsn.Rename) []tsn.Rename {
+	var sources = renameSources(operations)
+	var chained bool
+	for _, operation := range operations {
+		chained = chained || sources[operation.To]
 	}
-	if numberOfFiles < 1000 {
-		return "%03d-%s%s"
+	if !chained {
+		return operations
 	}
-	if numberOfFiles < 10000 {
-		return "%04d-%s%s"
+	// all temporary names are checked before moving anything:
+	for _, operation := range operations {
+		if temporary := temporaryName(operation.From); fileExists(temporary) {
+			Raise(temporary, "exists on file system")
+		}
 	}
-	if numberOfFiles < 100000 {
-		return "%05d-%s%s"
+	var staged = make([]tsn.Rename, len(operations))
+	copy(staged, operations)
+	for index, operation := range operations {
+		var temporary = temporaryName(operation.From)
+		if renameErr := os.Rename(operation.From, temporary); renameErr != nil {
+			unstageRenames(staged[:index], operations[:index])
+			CatchFile(renameErr, operation.From, "rename to temporary name")
+		}
+		staged[index].From = temporary
 	}
-	RaiseFmt("too many files: %d", numberOfFiles)
-	return ""
+	return staged
+}
+
+func temporaryName(name string) string {
+	return ".timestampname-" + name
 }
 
-func prepareRenameOperations(files []fileMetadata, noPrefix bool) ([]renameOperation, int) {
-	sort.Slice(files, func(i, j int) bool {
-		a := files[i]
-		b := files[j]
-		if a.metadataCreationTimestamp == b.metadataCreationTimestamp {
-			if a.name == b.name {
-				Raise(a.name, "encountered twice")
-			}
-			// workaround for Android way of dealing with same-second shots:
-			// 20180430_184327.jpg
-			// 20180430_184327(0).jpg
-			aLen := len(a.name)
-			bLen := len(b.name)
-			if aLen == bLen {
-				return a.name < b.name
-			}
-			return aLen < bLen
+// unstageRenames moves staged files back to their original names,
+// a file is left under temporary name with a warning if its original name got taken.
+func unstageRenames(staged []tsn.Rename, operations []tsn.Rename) {
+	for index, operation := range staged {
+		var original = operations[index].From
+		if operation.From == original {
+			continue
 		}
-		return a.metadataCreationTimestamp < b.metadataCreationTimestamp
-	})
+		if fileExists(original) {
+			warn(operation.From, "original name %s is taken, file left under temporary name", original)
+			continue
+		}
+		if err := os.Rename(operation.From, original); err != nil {
+			warn(operation.From, "restoring original name %s failed: %v", original, err)
+		}
+	}
+}
 
-	var operations = make([]renameOperation, len(files))
-	var targetFormat = targetFileNameFormat(len(files), noPrefix)
-	var longestSourceName int
+// timesJournal keeps original times of files changed by -set-mtime and -set-atime,
+// access time is empty where the platform does not report it.
+const timesJournal = ".timestampname-times.csv"
 
-	for index, md := range files {
-		var targetName = fmt.Sprintf(targetFormat, index+1, md.metadataCreationTimestamp, md.ext)
-		operations[index] = renameOperation{md.name, targetName}
-		// choosing longest source file name for next operation:
-		sourceNameLength := len(md.name)
-		if sourceNameLength > longestSourceName {
-			longestSourceName = sourceNameLength
-		}
+var timesJournalHeader = []string{"file", "atime", "mtime"}
+
+// applyTimes sets file times to the timestamp, before attributes may forbid it.
+// Original times are journaled first, times are left alone if that fails.
+func applyTimes(name string, timestamp time.Time) {
+	if !cmdArgs.setMtime && !cmdArgs.setAtime {
+		return
 	}
+	if err := journalTimes(name); err != nil {
+		warn(name, "recording original file times failed, times not set: %v", err)
+		return
+	}
+	// zero time leaves file time unchanged:
+	var mtime, atime time.Time
+	if cmdArgs.setMtime {
+		mtime = timestamp
+	}
+	if cmdArgs.setAtime {
+		atime = timestamp
+	}
+	if err := os.Chtimes(name, atime, mtime); err != nil {
+		warn(name, "setting file times failed: %v", err)
+	}
+}
 
-	return operations, longestSourceName
+// journalTimes appends current times of the file to the journal.
+func journalTimes(name string) error {
+	stat, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	var atime string
+	if accessTime, known := fileAccessTime(stat); known {
+		atime = accessTime.Format(time.RFC3339Nano)
+	}
+	journal, err := os.OpenFile(timesJournal, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	journalStat, err := journal.Stat()
+	if err != nil {
+		journal.Close()
+		return err
+	}
+	var writer = csv.NewWriter(journal)
+	if journalStat.Size() == 0 {
+		writer.Write(timesJournalHeader)
+	}
+	writer.Write([]string{name, atime, stat.ModTime().Format(time.RFC3339Nano)})
+	writer.Flush()
+	if err = writer.Error(); err != nil {
+		journal.Close()
+		return err
+	}
+	return journal.Close()
 }
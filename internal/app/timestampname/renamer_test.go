@@ -0,0 +1,88 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheIndifferent/timestampname-go/internal/golden"
+	tsn "github.com/TheIndifferent/timestampname-go/pkg/timestampname"
+)
+
+func renameFile(name string, timestamp string, source string, camera tsn.Camera) fileMetadata {
+	parsed, err := time.Parse("2006-01-02 15:04:05", timestamp)
+	if err != nil {
+		panic(err)
+	}
+	return fileMetadata{inputFile: inputFile{name: name}, Result: tsn.Result{Time: parsed, Source: source, Camera: camera}}
+}
+
+// planning itself is tested by the library, this covers the file metadata
+// passed to it and the padding of source names used by verifyOperations:
+func TestPrepareRenameOperations(t *testing.T) {
+	var canon = tsn.Camera{Make: "Canon", Model: "Canon EOS R5", Serial: "012345"}
+	var files = []fileMetadata{
+		renameFile("IMG_0002.JPG", "2021-05-06 07:08:10", tsn.SourceOriginal, canon),
+		renameFile("IMG_0001.JPG", "2021-05-06 07:08:09", tsn.SourceDigitized, canon),
+		renameFile("clip.MP4", "2021-05-06 06:00:00", tsn.SourceGps, tsn.Camera{}),
+		renameFile("GOPR0001_long_name.MP4", "2021-05-06 06:30:00", tsn.SourceModified, tsn.Camera{Make: "GoPro", Model: "HERO9 Black"}),
+	}
+	operations, longestSourceName := prepareRenameOperations(files, tsn.PlanOptions{Camera: true})
+	var sb strings.Builder
+	for _, operation := range operations {
+		fmt.Fprintf(&sb, "%[3]*[1]s => %[2]s (%[4]s)\n", operation.From, operation.To, longestSourceName, operation.Source)
+	}
+	golden.Check(t, "rename", sb.String())
+}
+
+func TestApplyTimesJournal(t *testing.T) {
+	var dir = t.TempDir()
+	workDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(workDir)
+	defer func(args commandLineArguments) { cmdArgs = args }(cmdArgs)
+	cmdArgs.setMtime = true
+
+	var original = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var timestamp = time.Date(2021, 5, 6, 7, 8, 9, 0, time.UTC)
+	for _, name := range []string{"a.jpg", "b.jpg"} {
+		if err = os.WriteFile(name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.Chtimes(name, original, original); err != nil {
+			t.Fatal(err)
+		}
+		applyTimes(name, timestamp)
+		stat, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !stat.ModTime().Equal(timestamp) {
+			t.Errorf("%s: got mtime %v, want %v", name, stat.ModTime(), timestamp)
+		}
+	}
+	journal, err := os.ReadFile(timesJournal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines = strings.Split(strings.TrimSpace(string(journal)), "\n")
+	if len(lines) != 3 || lines[0] != "file,atime,mtime" {
+		t.Fatalf("unexpected journal:\n%s", journal)
+	}
+	for index, name := range []string{"a.jpg", "b.jpg"} {
+		var fields = strings.Split(lines[index+1], ",")
+		if fields[0] != name || fields[2] != original.Local().Format(time.RFC3339Nano) {
+			t.Errorf("got journal line %q for %s", lines[index+1], name)
+		}
+	}
+}
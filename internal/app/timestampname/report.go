@@ -0,0 +1,205 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	tsn "github.com/TheIndifferent/timestampname-go/pkg/timestampname"
+)
+
+const reportJson = "json"
+
+// exit codes, partial failure means some files were processed and some failed:
+const (
+	exitSuccess        = 0
+	exitFailure        = 1
+	exitPartialFailure = 2
+)
+
+type reportFile struct {
+	File      string `json:"file"`
+	Target    string `json:"target,omitempty"`
+	Timestamp string `json:"timestamp,omitempty"`
+	Source    string `json:"source,omitempty"`
+	Tag       string `json:"tag,omitempty"`
+	Camera    string `json:"camera,omitempty"`
+	Renamed   bool   `json:"renamed"`
+	// DuplicateOf names the file with the same content:
+	DuplicateOf string   `json:"duplicateOf,omitempty"`
+	Warnings    []string `json:"warnings,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+type reportSkipped struct {
+	File   string `json:"file"`
+	Reason string `json:"reason"`
+}
+
+// runReport is the structured summary of a run, files that fail are reported
+// and the run continues with the rest.
+type runReport struct {
+	Started     string          `json:"started"`
+	Finished    string          `json:"finished"`
+	DurationMs  int64           `json:"durationMs"`
+	DryRun      bool            `json:"dryRun"`
+	Scanned     int             `json:"scanned"`
+	Renamed     int             `json:"renamed"`
+	Failed      int             `json:"failed"`
+	Files       []*reportFile   `json:"files"`
+	Skipped     []reportSkipped `json:"skipped"`
+	Error       string          `json:"error,omitempty"`
+	ExitCode    int             `json:"exitCode"`
+	started     time.Time
+	filesByName map[string]*reportFile
+}
+
+// report is nil unless requested, methods are safe to call on nil.
+var report *runReport
+
+func newRunReport(dryRun bool) *runReport {
+	var now = time.Now()
+	return &runReport{
+		Started:     now.Format(time.RFC3339Nano),
+		DryRun:      dryRun,
+		Files:       []*reportFile{},
+		Skipped:     []reportSkipped{},
+		started:     now,
+		filesByName: make(map[string]*reportFile),
+	}
+}
+
+func (r *runReport) file(name string) *reportFile {
+	if f, exists := r.filesByName[name]; exists {
+		return f
+	}
+	var f = &reportFile{File: name}
+	r.filesByName[name] = f
+	r.Files = append(r.Files, f)
+	return f
+}
+
+func (r *runReport) skip(name string, reason string) {
+	if r != nil {
+		r.Skipped = append(r.Skipped, reportSkipped{File: name, Reason: reason})
+	}
+}
+
+func (r *runReport) duplicate(name string, original string, skipped bool) {
+	if r != nil {
+		r.file(name).DuplicateOf = original
+		if skipped {
+			r.skip(name, "duplicate of "+original)
+		}
+	}
+}
+
+func (r *runReport) scanned(count int) {
+	if r != nil {
+		r.Scanned = count
+	}
+}
+
+func (r *runReport) extracted(md fileMetadata) {
+	if r != nil {
+		var f = r.file(md.name)
+		f.Timestamp = md.Time.Format(tsn.TimestampLayout)
+		f.Source = md.Source
+		f.Tag = md.Tag
+		f.Camera = md.Camera.String()
+	}
+}
+
+func (r *runReport) planned(operations []tsn.Rename) {
+	if r != nil {
+		for _, operation := range operations {
+			var f = r.file(operation.From)
+			f.Target = operation.To
+			f.Source = operation.Source
+		}
+	}
+}
+
+func (r *runReport) renamed(operation tsn.Rename) {
+	if r != nil {
+		r.file(operation.From).Renamed = true
+		r.Renamed++
+	}
+}
+
+// warned records a problem that did not fail the file, name may be the renamed one.
+func (r *runReport) warned(name string, message string) {
+	if r != nil {
+		for _, f := range r.Files {
+			if f.File == name || (f.Renamed && f.Target == name) {
+				f.Warnings = append(f.Warnings, message)
+				return
+			}
+		}
+		r.file(name).Warnings = append(r.file(name).Warnings, message)
+	}
+}
+
+func (r *runReport) failed(name string, err error) {
+	if r == nil {
+		// watching goes on past failing files:
+		fmt.Fprintf(os.Stderr, "\n\033[31mFailure: %s: %v\033[0m\n", name, err)
+		return
+	}
+	r.file(name).Error = err.Error()
+	r.Failed++
+}
+
+// tryFile runs the function, failures are returned when reporting or watching and raised otherwise.
+func tryFile(f func()) (err error) {
+	if report == nil && !cmdArgs.watch {
+		f()
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			if f, isFailure := r.(*failure); isFailure {
+				err = errors.New(f.summary())
+			} else {
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+	f()
+	return nil
+}
+
+// finish computes the exit code and writes the report to standard output.
+func (r *runReport) finish(fatal interface{}) {
+	var now = time.Now()
+	r.Finished = now.Format(time.RFC3339Nano)
+	r.DurationMs = now.Sub(r.started).Milliseconds()
+	switch {
+	case fatal != nil:
+		if f, isFailure := fatal.(*failure); isFailure && len(f.file) > 0 {
+			r.Error = f.file + ": " + f.summary()
+		} else if isFailure {
+			r.Error = f.summary()
+		} else {
+			r.Error = fmt.Sprintf("%v", fatal)
+		}
+		r.ExitCode = exitFailure
+	case r.Failed == 0:
+		r.ExitCode = exitSuccess
+	case r.Failed < len(r.Files):
+		r.ExitCode = exitPartialFailure
+	default:
+		r.ExitCode = exitFailure
+	}
+	var encoder = json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(r); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write report: %v\n", err)
+		r.ExitCode = exitFailure
+	}
+}
@@ -6,30 +6,178 @@ package timestampname
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"runtime"
 	"strconv"
+	"strings"
 	"time"
+
+	tsn "github.com/TheIndifferent/timestampname-go/pkg/timestampname"
 )
 
 //
 // BEFORE INITIALIZATION
 //
 
+const (
+	commandRename  = "rename"
+	commandInspect = "inspect"
+	commandClocks  = "clocks"
+)
+
 type commandLineArguments struct {
-	dryRun      bool
-	noPrefix    bool
-	debugOutput bool
-	timezone    *time.Location
+	command       string
+	files         []string
+	jsonOutput    bool
+	dryRun        bool
+	duplicates    string
+	hash          string
+	noPrefix      bool
+	camera        bool
+	syncClocks    bool
+	syncUnknown   bool
+	append        bool
+	onCollision   string
+	chmod         string
+	shift         time.Duration
+	writeMetadata bool
+	backup        bool
+	setMtime      bool
+	watch         bool
+	setAtime      bool
+	attributes    string
+	debugOutput   bool
+	timezone      *time.Location
+	xmp           string
+	exifGps       string
+	fromFilename  bool
+	fromMtime     bool
+	precedence    [][]string
+	planOut       string
+	applyPlan     string
+	report        string
 }
 
 func parseCommandLineArguments() commandLineArguments {
 	var cmdArgs commandLineArguments
+	cmdArgs.command = commandRename
+	var args = os.Args[1:]
+	// subcommand goes before flags:
+	if len(args) > 0 && (args[0] == commandInspect || args[0] == commandClocks) {
+		cmdArgs.command = args[0]
+		args = args[1:]
+	}
 	flag.BoolVar(&cmdArgs.dryRun, "dry", false, "dry run")
 	flag.BoolVar(&cmdArgs.noPrefix, "noprefix", false, "no counter prefix")
+	flag.BoolVar(&cmdArgs.camera, "camera", false, "append camera make and model to target names, for sorting shots of several cameras apart")
+	flag.BoolVar(&cmdArgs.syncClocks, "sync-clocks", false, "propose clock offsets of cameras from overlapping shots and Exif GPS time, apply them after confirmation")
+	flag.BoolVar(&cmdArgs.syncUnknown, "sync-unknown", false, "with -sync-clocks, synchronise files without camera make and model as one more camera")
+	var shiftString string
+	flag.StringVar(&shiftString, "shift", "0s", "correction of camera clock added to timestamps recorded by it, for example -1h30m or 45s")
+	flag.BoolVar(&cmdArgs.writeMetadata, "write-metadata", false, "write timestamps corrected by -shift into Exif and QuickTime metadata of renamed files; shifts again on every run")
+	flag.BoolVar(&cmdArgs.backup, "backup", true, "keep copy of the file with '"+backupSuffix+"' suffix before writing metadata")
+	flag.BoolVar(&cmdArgs.watch, "watch", false, "keep watching the folder and rename new files after the existing counter sequence, implies -append")
+	flag.BoolVar(&cmdArgs.setMtime, "set-mtime", false, "set modification time of renamed files to their timestamp, original times are appended to '"+timesJournal+"'")
+	flag.BoolVar(&cmdArgs.setAtime, "set-atime", false, "set access time of renamed files to their timestamp, original times are appended to '"+timesJournal+"'")
+	flag.StringVar(&cmdArgs.chmod, "chmod", "0444", "permissions of renamed files: octal mode, keep to leave them unchanged, or none to change neither permissions nor attributes")
+	flag.StringVar(&cmdArgs.attributes, "attr", attributesNone, "file attribute set on renamed files where supported: immutable or append; such files cannot be renamed again until the attribute is cleared")
+	flag.StringVar(&cmdArgs.onCollision, "on-collision", tsn.CollisionAbort, "target name collision policy: abort, suffix, subsec or skip")
+	flag.StringVar(&cmdArgs.duplicates, "duplicates", duplicatesOff, "byte-identical duplicates detection: off, report, skip or move to '"+duplicatesFolder+"' folder")
+	flag.StringVar(&cmdArgs.hash, "hash", hashPartial, "content hash for duplicates detection: full, or partial hashing size, beginning and end of files; partial matches are compared in full")
+	flag.BoolVar(&cmdArgs.append, "append", false, "keep files already named after their timestamp, number new files after the highest counter")
 	flag.BoolVar(&cmdArgs.debugOutput, "debug", false, "debug output")
 	var zoneOffsetString string
 	flag.StringVar(&zoneOffsetString, "timezone", "0", "time zone where the video was taken. May be signed, single digit or 4 digits.")
-	flag.Parse()
+	flag.StringVar(&cmdArgs.xmp, "xmp", precedenceFallback, "XMP timestamp precedence relative to embedded metadata: fallback, prefer or ignore")
+	flag.StringVar(&cmdArgs.exifGps, "exif-gps", precedenceIgnore, "Exif GPS UTC time precedence relative to embedded metadata: fallback, prefer or ignore; converted to -timezone")
+	flag.BoolVar(&cmdArgs.fromFilename, "from-filename", false, "fall back to timestamp in file name when metadata has none")
+	flag.BoolVar(&cmdArgs.fromMtime, "from-mtime", false, "fall back to file modification time when metadata has none")
+	var preferString string
+	flag.StringVar(&preferString, "prefer", "", "comma separated timestamp sources in order of preference: "+strings.Join(tsn.Sources, ",")+"; overrides -xmp, -exif-gps, -from-filename and -from-mtime")
+	flag.BoolVar(&cmdArgs.jsonOutput, "json", false, "JSON output of inspect command")
+	flag.StringVar(&cmdArgs.planOut, "plan-out", "", "write rename plan to JSON or CSV file for review instead of renaming")
+	flag.StringVar(&cmdArgs.applyPlan, "apply-plan", "", "rename files according to reviewed plan file")
+	flag.StringVar(&cmdArgs.report, "report", "", "write structured run report to standard output and continue past failing files: json")
+	var profile string
+	var configLocations = "./" + folderConfigFile
+	if userConfig := userConfigPath(); len(userConfig) > 0 {
+		configLocations = userConfig + " or " + configLocations
+	}
+	flag.StringVar(&profile, "profile", "", "profile of configuration file "+configLocations+"; options given on command line override configured ones")
+	flag.CommandLine.Parse(args)
+	applyConfig(profile)
+	cmdArgs.files = flag.Args()
+	if cmdArgs.command == commandInspect && len(cmdArgs.files) == 0 {
+		RaiseFmt("usage: %s inspect [flags] FILE...", os.Args[0])
+	}
+	if len(cmdArgs.planOut) > 0 && len(cmdArgs.applyPlan) > 0 {
+		RaiseFmt("-plan-out and -apply-plan are mutually exclusive")
+	}
+	var err error
+	cmdArgs.shift, err = time.ParseDuration(shiftString)
+	Catch(err, "invalid -shift")
+	if cmdArgs.shift%time.Second != 0 {
+		RaiseFmt("invalid -shift, whole seconds expected: %s", shiftString)
+	}
+	if cmdArgs.writeMetadata && cmdArgs.shift == 0 {
+		RaiseFmt("-write-metadata requires -shift")
+	}
+	parseChmod(cmdArgs.chmod)
+	switch cmdArgs.attributes {
+	case attributesNone, attributesImmutable, attributesAppend:
+	default:
+		RaiseFmt("invalid file attribute: %s", cmdArgs.attributes)
+	}
+	switch cmdArgs.onCollision {
+	case tsn.CollisionAbort, tsn.CollisionSuffix, tsn.CollisionSubsec, tsn.CollisionSkip:
+	default:
+		RaiseFmt("invalid collision policy: %s", cmdArgs.onCollision)
+	}
+	switch cmdArgs.duplicates {
+	case duplicatesOff, duplicatesReport, duplicatesSkip, duplicatesMove:
+	default:
+		RaiseFmt("invalid duplicates mode: %s", cmdArgs.duplicates)
+	}
+	if cmdArgs.hash != hashFull && cmdArgs.hash != hashPartial {
+		RaiseFmt("invalid hash mode: %s", cmdArgs.hash)
+	}
+	if cmdArgs.watch {
+		// checked before any file is renamed:
+		if runtime.GOOS != "linux" {
+			RaiseFmt("-watch requires inotify, available on Linux only")
+		}
+		if cmdArgs.dryRun || len(cmdArgs.planOut) > 0 || len(cmdArgs.applyPlan) > 0 || len(cmdArgs.report) > 0 {
+			RaiseFmt("-watch cannot be combined with -dry, -plan-out, -apply-plan or -report")
+		}
+		cmdArgs.append = true
+	}
+	if cmdArgs.syncClocks && (cmdArgs.watch || len(cmdArgs.applyPlan) > 0) {
+		RaiseFmt("-sync-clocks cannot be combined with -watch or -apply-plan")
+	}
+	if cmdArgs.syncUnknown && !cmdArgs.syncClocks {
+		RaiseFmt("-sync-unknown requires -sync-clocks")
+	}
+	if len(cmdArgs.report) > 0 && cmdArgs.report != reportJson {
+		RaiseFmt("invalid report format: %s", cmdArgs.report)
+	}
+
+	switch cmdArgs.xmp {
+	case precedenceFallback, precedencePrefer, precedenceIgnore:
+	default:
+		RaiseFmt("invalid XMP precedence: %s", cmdArgs.xmp)
+	}
+	switch cmdArgs.exifGps {
+	case precedenceFallback, precedencePrefer, precedenceIgnore:
+	default:
+		RaiseFmt("invalid Exif GPS precedence: %s", cmdArgs.exifGps)
+	}
+	if len(preferString) > 0 {
+		cmdArgs.precedence, err = tsn.ParsePrecedence(preferString)
+		Catch(err, "invalid -prefer")
+	} else {
+		cmdArgs.precedence = defaultPrecedence(cmdArgs.xmp, cmdArgs.exifGps, cmdArgs.fromFilename, cmdArgs.fromMtime)
+	}
 
 	// parsing zone offset:
 	switch len(zoneOffsetString) {
@@ -74,6 +222,8 @@ func parseCommandLineArguments() commandLineArguments {
 var (
 	cmdArgs commandLineArguments
 	workDir string
+	// progress goes to standard error when standard output carries the report:
+	logOutput io.Writer = os.Stdout
 )
 
 //
@@ -82,56 +232,103 @@ var (
 
 func debug(format string, a ...interface{}) {
 	if cmdArgs.debugOutput {
-		fmt.Fprintf(os.Stdout, "\033[32m"+format+"\033[0m\n", a...)
+		fmt.Fprintf(logOutput, "\033[32m"+format+"\033[0m\n", a...)
 	}
 }
 
 func info(format string, a ...interface{}) {
-	fmt.Fprintf(os.Stdout, format, a...)
+	fmt.Fprintf(logOutput, format, a...)
+}
+
+func warn(file string, format string, a ...interface{}) {
+	var message = fmt.Sprintf(format, a...)
+	fmt.Fprintf(logOutput, "\n\033[33mWarning: %s: %s\033[0m\n", file, message)
+	report.warned(file, message)
 }
 
 //
 // END LOGGING
 //
 
-func processFiles(files []inputFile) []fileMetadata {
+func processFiles(files []inputFile, duplicates *duplicateDetector) []fileMetadata {
 	var total = len(files)
-	var output = make([]fileMetadata, total)
+	var output = make([]fileMetadata, 0, total)
 	for index, file := range files {
 		info("\rProcessing files: %d/%d...", index+1, total)
-		output[index] = fileMetadataCreationTimestamp(file)
+		var original string
+		var skipDuplicate bool
+		err := tryFile(func() {
+			if duplicates != nil {
+				original = duplicates.check(file.name)
+				skipDuplicate = len(original) > 0 && duplicates.mode != duplicatesReport
+			}
+			if !skipDuplicate {
+				output = append(output, fileMetadataCreationTimestamp(file))
+			}
+		})
+		if len(original) > 0 {
+			report.duplicate(file.name, original, skipDuplicate)
+		}
+		if skipDuplicate {
+			continue
+		}
+		if err != nil {
+			report.failed(file.name, err)
+			continue
+		}
+		report.extracted(output[len(output)-1])
 	}
 	info(" done.\n")
 	return output
 }
 
-func verifyOperations(operations []renameOperation, longestSourceName int) {
-	duplicatesMap := make(map[string]string)
+func verifyOperations(operations []tsn.Rename, longestSourceName int) {
+	var sources = renameSources(operations)
 	for _, operation := range operations {
-		info("    %[3]*[1]s    =>    %[2]s\n", operation.from, operation.to, longestSourceName)
-		// check for target name duplicates:
-		if _, existsInMap := duplicatesMap[operation.to]; existsInMap {
-			Raise(operation.to, "duplicate rename")
-		} else {
-			duplicatesMap[operation.to] = operation.to
-		}
-		// check for renaming duplicates:
-		if operation.from != operation.to {
-			if _, existsInDir := os.Stat(operation.to); existsInDir == nil {
-				Raise(operation.to, "exists on file system")
+		info("    %[3]*[1]s    =>    %[2]s    (%[4]s)\n", operation.From, operation.To, longestSourceName, operation.Source)
+		// check for renaming duplicates, target may be freed by another rename:
+		if operation.From != operation.To && !sources[operation.To] {
+			if _, existsInDir := os.Stat(operation.To); existsInDir == nil {
+				Raise(operation.To, "exists on file system")
 			}
 		}
 	}
 }
 
-func executeOperations(operations []renameOperation, dryRun bool) {
-	for index, operation := range operations {
-		info("\rRenaming files: %d/%d", index+1, len(operations))
+func executeOperations(operations []tsn.Rename, dryRun bool) {
+	var staged = operations
+	if !dryRun {
+		staged = stageChainedRenames(operations)
+	}
+	// failure leaves files not renamed yet under their original names:
+	var renamed int
+	defer func() {
+		if r := recover(); r != nil {
+			unstageRenames(staged[renamed:], operations[renamed:])
+			panic(r)
+		}
+	}()
+	for index, operation := range staged {
+		info("\rRenaming files: %d/%d", index+1, len(staged))
 		if !dryRun {
-			renameErr := os.Rename(operation.from, operation.to)
-			CatchFile(renameErr, operation.from, "rename")
-			chmodErr := os.Chmod(operation.to, 0444)
-			CatchFile(chmodErr, operation.from, "chmod")
+			err := tryFile(func() {
+				renameErr := os.Rename(operation.From, operation.To)
+				CatchFile(renameErr, operation.From, "rename")
+			})
+			renamed = index + 1
+			if err != nil {
+				report.failed(operations[index].From, err)
+				unstageRenames(staged[index:index+1], operations[index:index+1])
+				continue
+			}
+			report.renamed(operations[index])
+			if cmdArgs.writeMetadata {
+				writeMetadata(operation.To, false)
+			}
+			applyTimes(operation.To, operation.Time)
+			applyPermissions(operation.To)
+		} else if cmdArgs.writeMetadata {
+			writeMetadata(operation.From, true)
 		}
 	}
 	info(" done.\n")
@@ -140,13 +337,47 @@ func executeOperations(operations []renameOperation, dryRun bool) {
 func Exec() {
 
 	defer func() {
-		if r := recover(); r != nil {
+		r := recover()
+		if report != nil {
+			report.finish(r)
+			os.Exit(report.ExitCode)
+		}
+		if r != nil {
 			fmt.Fprintf(os.Stderr, "\n\033[31m%v\033[0m\n", r)
-			os.Exit(1)
+			os.Exit(exitFailure)
 		}
 	}()
 
 	cmdArgs = parseCommandLineArguments()
+	if cmdArgs.report == reportJson && cmdArgs.command == commandRename {
+		logOutput = os.Stderr
+		report = newRunReport(cmdArgs.dryRun)
+	}
+	if cmdArgs.debugOutput {
+		tsn.SetDebugLogger(debug)
+	}
+	if cmdArgs.command == commandInspect {
+		inspectFiles(cmdArgs.files, cmdArgs.jsonOutput)
+		return
+	}
+	if cmdArgs.command == commandClocks {
+		reportClocks(clocksFiles(cmdArgs.files))
+		return
+	}
+
+	if len(cmdArgs.applyPlan) > 0 {
+		info("Reading plan %s...", cmdArgs.applyPlan)
+		operations, longestSourceName := validatePlan(cmdArgs.applyPlan, readPlan(cmdArgs.applyPlan))
+		info(" %d rename operations.\n", len(operations))
+		report.scanned(len(operations))
+		report.planned(operations)
+		info("Verifying:\n")
+		verifyOperations(operations, longestSourceName)
+		info("done.\n")
+		executeOperations(operations, cmdArgs.dryRun)
+		info("\nFinished.\n")
+		return
+	}
 
 	info("Scanning for files... ")
 	var err error
@@ -154,15 +385,68 @@ func Exec() {
 	Catch(err, "failed to get current working directory")
 	var inputFiles = listFiles(workDir)
 	info("%d supported files found.\n", len(inputFiles))
+	report.scanned(len(inputFiles))
+
+	var duplicates = newDuplicateDetector(cmdArgs.duplicates, cmdArgs.hash)
+	var renamed = renameFiles(inputFiles, duplicates, nil)
+	if cmdArgs.watch {
+		watchFolder(workDir, duplicates, renamed)
+	}
+}
 
-	metadatas := processFiles(inputFiles)
+// renameFiles extracts timestamps of the files and renames them together with the known ones,
+// returns metadata of all the files under their new names.
+func renameFiles(files []inputFile, duplicates *duplicateDetector, known []fileMetadata) []fileMetadata {
+	var duplicatesFound int
+	if duplicates != nil {
+		duplicatesFound = len(duplicates.found)
+	}
+	var metadatas = append(append([]fileMetadata{}, known...), processFiles(files, duplicates)...)
+	if duplicates != nil {
+		printDuplicates(duplicates.found[duplicatesFound:])
+	}
+	if cmdArgs.syncClocks {
+		syncClocks(metadatas)
+	}
 	info("Preparing rename operations...")
-	operations, longestSourceName := prepareRenameOperations(metadatas, cmdArgs.noPrefix)
+	operations, longestSourceName := prepareRenameOperations(metadatas, tsn.PlanOptions{
+		NoPrefix:    cmdArgs.noPrefix,
+		Camera:      cmdArgs.camera,
+		Append:      cmdArgs.append,
+		OnCollision: cmdArgs.onCollision,
+		Exists:      fileExists,
+	})
 	info(" done.\n")
+	report.planned(operations)
+	var pending = pendingOperations(operations)
 
 	info("Verifying:\n")
-	verifyOperations(operations, longestSourceName)
+	verifyOperations(pending, longestSourceName)
 	info("done.\n")
-	executeOperations(operations, cmdArgs.dryRun)
+	if len(cmdArgs.planOut) > 0 {
+		writePlan(cmdArgs.planOut, pending, metadatas)
+		info("Plan written to %s\n", cmdArgs.planOut)
+		return metadatas
+	}
+	if duplicates != nil && duplicates.mode == duplicatesMove {
+		moveDuplicates(duplicates.found[duplicatesFound:], cmdArgs.dryRun)
+	}
+	executeOperations(pending, cmdArgs.dryRun)
 	info("\nFinished.\n")
+
+	if !cmdArgs.dryRun {
+		var targets = make(map[string]string)
+		for _, operation := range pending {
+			targets[operation.From] = operation.To
+		}
+		for index, md := range metadatas {
+			if target, renamed := targets[md.name]; renamed {
+				metadatas[index].name = target
+			}
+		}
+		if duplicates != nil {
+			duplicates.renamed(targets)
+		}
+	}
+	return metadatas
 }
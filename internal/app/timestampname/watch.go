@@ -0,0 +1,66 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tsn "github.com/TheIndifferent/timestampname-go/pkg/timestampname"
+)
+
+// watchSettleInterval is how long size and modification time of a new file must stay unchanged.
+const watchSettleInterval = 500 * time.Millisecond
+
+// watchRename renames newly arrived files after the known ones,
+// failing files are printed and left out, failures of the whole batch do not stop watching.
+func watchRename(names []string, duplicates *duplicateDetector, known []fileMetadata) (renamed []fileMetadata) {
+	var knownNames = make(map[string]bool)
+	for _, md := range known {
+		knownNames[md.name] = true
+	}
+	var files []inputFile
+	for _, name := range names {
+		// own renames, hidden and temporary files:
+		if knownNames[name] || strings.HasPrefix(name, ".") || !tsn.Supported(name) {
+			continue
+		}
+		knownNames[name] = true
+		if waitStable(name) {
+			files = append(files, inputFile{name: name})
+		}
+	}
+	if len(files) == 0 {
+		return known
+	}
+
+	renamed = known
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(os.Stderr, "\n\033[31m%v\033[0m\n", r)
+		}
+		info("Watching for new files...\n")
+	}()
+	info("%d new files.\n", len(files))
+	return renameFiles(files, duplicates, known)
+}
+
+// waitStable waits until the file stops changing, returns false if it disappeared.
+func waitStable(name string) bool {
+	var previous os.FileInfo
+	for {
+		stat, err := os.Stat(name)
+		if err != nil {
+			debug("new file disappeared: %s", name)
+			return false
+		}
+		if previous != nil && stat.Size() == previous.Size() && stat.ModTime().Equal(previous.ModTime()) {
+			return true
+		}
+		previous = stat
+		time.Sleep(watchSettleInterval)
+	}
+}
@@ -0,0 +1,53 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package timestampname
+
+import (
+	"bytes"
+	"syscall"
+	"unsafe"
+)
+
+// watchFolder renames files written or moved into the folder until interrupted.
+func watchFolder(folder string, duplicates *duplicateDetector, known []fileMetadata) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	Catch(err, "failed to initialize inotify")
+	defer syscall.Close(fd)
+	// files are complete once closed after writing or moved in:
+	_, err = syscall.InotifyAddWatch(fd, folder, syscall.IN_CLOSE_WRITE|syscall.IN_MOVED_TO)
+	CatchFile(err, folder, "failed to watch folder")
+
+	var buffer = make([]byte, 64*1024)
+	info("Watching for new files...\n")
+	for {
+		n, err := syscall.Read(fd, buffer)
+		if err == syscall.EINTR {
+			continue
+		}
+		Catch(err, "failed to read inotify events")
+		known = watchRename(inotifyNames(buffer[:n]), duplicates, known)
+	}
+}
+
+// inotifyNames returns file names of the events, in order of arrival.
+func inotifyNames(events []byte) []string {
+	var names []string
+	for offset := 0; offset+syscall.SizeofInotifyEvent <= len(events); {
+		var event = (*syscall.InotifyEvent)(unsafe.Pointer(&events[offset]))
+		var nameStart = offset + syscall.SizeofInotifyEvent
+		var nameEnd = nameStart + int(event.Len)
+		if nameEnd > len(events) {
+			break
+		}
+		// name is padded with zeros:
+		var name = string(bytes.TrimRight(events[nameStart:nameEnd], "\x00"))
+		if event.Mask&syscall.IN_ISDIR == 0 && len(name) > 0 {
+			names = append(names, name)
+		}
+		offset = nameEnd
+	}
+	return names
+}
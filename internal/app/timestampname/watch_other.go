@@ -0,0 +1,10 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package timestampname
+
+func watchFolder(folder string, duplicates *duplicateDetector, known []fileMetadata) {
+	RaiseFmt("-watch requires inotify, available on Linux only")
+}
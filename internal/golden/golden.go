@@ -0,0 +1,37 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package golden compares output of tests to files in testdata,
+// running tests with -update rewrites the files.
+package golden
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// Check compares output to testdata/<name>.golden.
+func Check(t *testing.T, name string, output string) {
+	t.Helper()
+	var golden = filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(golden, []byte(output), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read golden file, run with -update to create it: %v", err)
+	}
+	if output != string(expected) {
+		t.Errorf("output differs from %s:\n%s\nexpected:\n%s", golden, output, expected)
+	}
+}
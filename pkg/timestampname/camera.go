@@ -0,0 +1,112 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"io"
+	"strings"
+)
+
+// Camera identifies the device that recorded the file, fields are empty if unknown.
+type Camera struct {
+	Make   string
+	Model  string
+	Serial string
+}
+
+// String returns the model prefixed with the make unless the model already starts with it.
+func (c Camera) String() string {
+	if len(c.Make) == 0 || strings.HasPrefix(strings.ToLower(c.Model), strings.ToLower(c.Make)) {
+		return c.Model
+	}
+	if len(c.Model) == 0 {
+		return c.Make
+	}
+	return c.Make + " " + c.Model
+}
+
+// merge fills fields unknown to this camera from the other one.
+func (c Camera) merge(other Camera) Camera {
+	if len(c.Make) == 0 {
+		c.Make = other.Make
+	}
+	if len(c.Model) == 0 {
+		c.Model = other.Model
+	}
+	if len(c.Serial) == 0 {
+		c.Serial = other.Serial
+	}
+	return c
+}
+
+// cameraExtract looks for camera identity in the file,
+// only built-in extractors know where it is stored.
+func cameraExtract(in reader, extractor Extractor) Camera {
+	var builtin, isBuiltin = extractor.(*builtinExtractor)
+	if !isBuiltin || builtin.camera == nil {
+		return Camera{}
+	}
+	var camera Camera
+	err := try(func() {
+		camera = builtin.camera(in)
+	})
+	if err != nil {
+		debug("failed to extract camera: %v", err)
+	}
+	return camera
+}
+
+// tiffCamera reads Make (0x010F), Model (0x0110) and BodySerialNumber (0xA431)
+// from any IFD except GPS one, tag numbers do not overlap otherwise.
+func tiffCamera(in reader) Camera {
+	var camera Camera
+	for _, ifd := range tiffWalkIfds(in) {
+		if ifd.name == tiffGpsIfd {
+			continue
+		}
+		camera = camera.merge(Camera{
+			Make:   _tiffCameraValue(ifd, 0x010F),
+			Model:  _tiffCameraValue(ifd, 0x0110),
+			Serial: _tiffCameraValue(ifd, 0xA431),
+		})
+	}
+	return camera
+}
+
+func _tiffCameraValue(ifd tiffIfd, tag uint16) string {
+	entry, found := ifd.entry(tag)
+	if !found || entry.typ != 2 {
+		return ""
+	}
+	return strings.TrimSpace(entry.ascii())
+}
+
+// quicktimeCamera reads ©mak and ©mod user data boxes of moov/udta.
+func quicktimeCamera(in reader) Camera {
+	udtaIn, err := quicktimeSearchBoxPath(in, "moov", "udta")
+	if err != nil {
+		return Camera{}
+	}
+	return Camera{
+		Make:  _quicktimeUserDataText(udtaIn, "\xa9mak"),
+		Model: _quicktimeUserDataText(udtaIn, "\xa9mod"),
+	}
+}
+
+// _quicktimeUserDataText reads the first text of user data box:
+// 2 bytes text length, 2 bytes language code, text.
+func _quicktimeUserDataText(udtaIn reader, boxType string) string {
+	box, err := quicktimeSearchBox(udtaIn, boxType)
+	if err != nil || box.Size() < 4 {
+		return ""
+	}
+	var text = make([]byte, box.Size())
+	_, err = io.ReadFull(box, text)
+	catchFile(err, udtaIn.Name(), "failed to read user data box")
+	var length = int(text[0])<<8 | int(text[1])
+	if 4+length > len(text) {
+		raiseFmtFile(udtaIn.Name(), "user data box '%s' text goes over box length", boxType)
+	}
+	return strings.TrimSpace(strings.TrimRight(string(text[4:4+length]), "\x00"))
+}
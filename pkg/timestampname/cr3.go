@@ -0,0 +1,69 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"time"
+)
+
+func init() {
+	Register(&builtinExtractor{
+		name:       "CR3",
+		extensions: []string{".cr3"},
+		match: func(header []byte) bool {
+			// CR3 is QuickTime container with 'crx ' major brand:
+			return quicktimeMatch(header) && len(header) >= 12 && string(header[4:12]) == "ftypcrx "
+		},
+		// more specific than MP4:
+		priority:  1,
+		extract:   cr3ExtractTimestampCandidates,
+		searchXmp: quicktimeSearchXmp,
+		shift:     cr3ShiftTimestamps,
+		camera:    cr3Camera,
+	})
+}
+
+func cr3ExtractTimestampCandidates(in reader) []Candidate {
+	cmt1, cmt2 := cr3SearchMetadata(in)
+	var candidates = cr3TagCandidates("CMT1", tiffExtractTimestampCandidates(cmt1))
+	return append(candidates, cr3TagCandidates("CMT2", tiffExtractTimestampCandidates(cmt2))...)
+}
+
+// cr3ShiftTimestamps shifts Exif dates of CMT1 and CMT2 boxes and QuickTime header times.
+func cr3ShiftTimestamps(in reader, shift time.Duration) []Patch {
+	cmt1, cmt2 := cr3SearchMetadata(in)
+	var patches = append(tiffShiftTimestamps(cmt1, shift), tiffShiftTimestamps(cmt2, shift)...)
+	return append(patches, quicktimeShiftTimestamps(in, shift)...)
+}
+
+// cr3Camera reads make and model from CMT1 box and serial number from CMT2 box.
+func cr3Camera(in reader) Camera {
+	cmt1, cmt2 := cr3SearchMetadata(in)
+	return tiffCamera(cmt1).merge(tiffCamera(cmt2))
+}
+
+// cr3SearchMetadata returns readers of CMT1 and CMT2 boxes, both are TIFF structures.
+func cr3SearchMetadata(in reader) (reader, reader) {
+	moovIn, err := quicktimeSearchBox(in, "moov")
+	catchFile(err, in.Name(), "failed to find moov box")
+	canonBox, err := quicktimeSearchUuidBox(moovIn, "85c0b687820f11e08111f4ce462b6a48")
+	catchFile(err, in.Name(), "failed to find Canon metadata box")
+
+	cmt1, err := quicktimeSearchBox(canonBox, "CMT1")
+	catchFile(err, in.Name(), "failed to find CMT1 box")
+
+	_, err = canonBox.Seek(0, 0)
+	catchFile(err, in.Name(), "failed to rewind")
+	cmt2, err := quicktimeSearchBox(canonBox, "CMT2")
+	catchFile(err, in.Name(), "failed to find CMT2 box")
+	return cmt1, cmt2
+}
+
+// cr3TagCandidates prefixes tag names with the box they were found in.
+func cr3TagCandidates(box string, candidates []Candidate) []Candidate {
+	for i := range candidates {
+		candidates[i].Tag = box + " " + candidates[i].Tag
+	}
+	return candidates
+}
@@ -0,0 +1,18 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+var debugLogger func(format string, a ...interface{})
+
+// SetDebugLogger enables tracing of the parsers, nil disables it.
+// Logger is not synchronized, it must not be changed while extraction is running.
+func SetDebugLogger(logger func(format string, a ...interface{})) {
+	debugLogger = logger
+}
+
+func debug(format string, a ...interface{}) {
+	if debugLogger != nil {
+		debugLogger(format, a...)
+	}
+}
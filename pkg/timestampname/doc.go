@@ -0,0 +1,9 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package timestampname extracts creation time from photo, video and audio files
+// and plans renaming them to a timestamp format.
+//
+// Functions return errors rather than exit the process and never print,
+// tracing of the parsers can be enabled with SetDebugLogger.
+package timestampname
@@ -0,0 +1,157 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// TimestampLayout is the format of timestamps in target file names.
+const TimestampLayout = "20060102-150405"
+
+// Options control how the creation time is selected among candidates.
+type Options struct {
+	// Zone used to present times recorded in UTC, nil means UTC.
+	Zone *time.Location
+	// Precedence of timestamp sources, within a group the earliest timestamp wins,
+	// DefaultPrecedence is used if nil.
+	Precedence [][]string
+	// CollectAll collects candidates from every source,
+	// including ones precedence does not mention, for diagnostics.
+	CollectAll bool
+}
+
+func (o Options) zone() *time.Location {
+	if o.Zone == nil {
+		return time.UTC
+	}
+	return o.Zone
+}
+
+func (o Options) precedence() [][]string {
+	if o.Precedence == nil {
+		return DefaultPrecedence
+	}
+	return o.Precedence
+}
+
+func (o Options) collects(source string) bool {
+	return o.CollectAll || precedenceContains(o.precedence(), source)
+}
+
+// Result of the creation time extraction.
+type Result struct {
+	// Time is the selected timestamp in the options zone.
+	Time   time.Time
+	Source string
+	Tag    string
+	// Reason explains why the timestamp was selected.
+	Reason string
+	// Camera that recorded the file, fields are empty if unknown.
+	Camera Camera
+	// Candidates are all timestamps found, including ones not selected.
+	Candidates []Candidate
+	// Extractor is the name of the format extractor used, empty if none matched.
+	Extractor string
+	// ExtractorErr is the failure of the extractor, other sources may still provide a timestamp.
+	ExtractorErr error
+}
+
+// ExtractCreationTime extracts creation time from the content,
+// hint is the file name or extension used to detect the format.
+// Sources that require file system, sidecars and modification time, are not available.
+// On failure result still carries candidates found, if any.
+func ExtractCreationTime(r io.ReaderAt, size int64, hint string, options Options) (result Result, err error) {
+	defer recoverError(&err)
+	var in = newReaderAt(r, size, hint)
+	result = collectCandidates(in, hint, options)
+	return result, selectResult(&result, hint, options)
+}
+
+// ExtractFileCreationTime extracts creation time from the file,
+// including XMP sidecar and file modification time if precedence mentions them.
+// On failure result still carries candidates found, if any.
+func ExtractFileCreationTime(path string, options Options) (result Result, err error) {
+	defer recoverError(&err)
+
+	openFile, openErr := os.Open(path)
+	catchFile(openErr, path, "failed to open")
+	defer func() {
+		closeErr := openFile.Close()
+		catchFile(closeErr, path, "failed to close")
+	}()
+
+	var in = newFileReader(openFile, path)
+	result = collectCandidates(in, path, options)
+	if options.collects(SourceXmp) && !containsSource(result.Candidates, SourceXmp) {
+		result.Candidates = append(result.Candidates, xmpExtractSidecarTimestampCandidates(path)...)
+	}
+	if options.collects(SourceMtime) {
+		result.Candidates = append(result.Candidates, mtimeExtractTimestampCandidates(path)...)
+	}
+	return result, selectResult(&result, path, options)
+}
+
+// collectCandidates collects timestamps from the content for every source options ask for.
+func collectCandidates(in reader, name string, options Options) Result {
+	var result Result
+	var header = make([]byte, HeaderLength)
+	n, _ := in.ReadAt(header, 0)
+	extractor, found := lookupExtractor(header[:n], extension(name))
+	if found {
+		debug("using %s extractor", extractor.Name())
+		result.Extractor = extractor.Name()
+		var err error
+		result.Candidates, err = extractor.Extract(in, in.Size(), name)
+		if err != nil {
+			debug("failed to extract embedded metadata: %v", err)
+			var libraryErr *Error
+			if !errors.As(err, &libraryErr) {
+				err = &Error{File: name, Descriptor: extractor.Name() + " extractor failed", Err: err}
+			}
+			result.ExtractorErr = err
+		}
+		result.Camera = cameraExtract(in, extractor)
+	} else {
+		result.ExtractorErr = &Error{File: name, Descriptor: "content does not match any known format"}
+	}
+	if options.collects(SourceXmp) {
+		result.Candidates = append(result.Candidates, xmpExtractTimestampCandidates(in, extractor)...)
+	}
+	if options.collects(SourceFilename) {
+		result.Candidates = append(result.Candidates, filenameExtractTimestampCandidates(name)...)
+	}
+	return result
+}
+
+func containsSource(candidates []Candidate, source string) bool {
+	for _, candidate := range candidates {
+		if candidate.Source == source {
+			return true
+		}
+	}
+	return false
+}
+
+// selectResult fills result with the selected candidate,
+// extractor failure is returned if no candidate is selected.
+func selectResult(result *Result, name string, options Options) error {
+	debug("timestamp candidates: %v", result.Candidates)
+	selected, reason, found := selectCandidate(result.Candidates, options.precedence(), options.zone())
+	if !found {
+		if result.ExtractorErr != nil {
+			return result.ExtractorErr
+		}
+		return &Error{File: name, Descriptor: "no timestamp found in any of preferred sources"}
+	}
+	debug("selected timestamp: %v, %s", selected, reason)
+	result.Time = selected.In(options.zone())
+	result.Source = selected.Source
+	result.Tag = selected.Tag
+	result.Reason = reason
+	return nil
+}
@@ -0,0 +1,175 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+)
+
+var testExif = fixtureExif{
+	make:           "Canon",
+	model:          "Canon EOS R5",
+	serial:         "012345",
+	dateTime:       "2021:05:07 10:00:00",
+	original:       "2021:05:06 07:08:09",
+	digitized:      "2021:05:06 07:08:10",
+	subSecOriginal: "25",
+}
+
+var testCamera = Camera{"Canon", "Canon EOS R5", "012345"}
+
+func byteOrderName(bo binary.ByteOrder) string {
+	if bo == binary.LittleEndian {
+		return "II"
+	}
+	return "MM"
+}
+
+type extractTest struct {
+	name    string
+	hint    string
+	data    []byte
+	options Options
+	time    time.Time
+	source  string
+	tag     string
+	camera  Camera
+}
+
+func extractTests() []extractTest {
+	var original = time.Date(2021, 5, 6, 7, 8, 9, 250*int(time.Millisecond), time.UTC)
+	var tests []extractTest
+	for _, bo := range fixtureByteOrders {
+		var gpsExif = testExif
+		gpsExif.gpsDate = "2021:05:06"
+		gpsExif.gpsTime = [3]float64{5, 8, 7.5}
+		var xmp = xmpFixture("exif:DateTimeOriginal", "2020-01-02T03:04:05+02:00")
+		var cases = []extractTest{
+			{name: "JPEG", hint: ".jpg", data: jpegFixture(tiffFixture(bo, testExif), nil),
+				time: original, source: SourceOriginal, tag: "DateTimeOriginal", camera: testCamera},
+			{name: "JPEG Exif GPS", hint: ".jpg", data: jpegFixture(tiffFixture(bo, gpsExif), nil),
+				options: Options{Precedence: [][]string{{SourceExifGps}}},
+				time:    time.Date(2021, 5, 6, 5, 8, 7, 500*int(time.Millisecond), time.UTC), source: SourceExifGps, tag: "GPSDateStamp GPSTimeStamp", camera: testCamera},
+			{name: "JPEG XMP", hint: ".jpg", data: jpegFixture(tiffFixture(bo, fixtureExif{make: "Canon"}), xmp),
+				time: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), source: SourceXmp, tag: "embedded exif:DateTimeOriginal", camera: Camera{Make: "Canon"}},
+			{name: "JPEG MPF", hint: ".jpg", data: jpegMpfFixture(bo, jpegFixture(tiffFixture(bo, testExif), nil)),
+				time: original, source: SourceOriginal, tag: "DateTimeOriginal", camera: testCamera},
+			{name: "DNG", hint: ".dng", data: dngFixture(bo, testExif),
+				time: original, source: SourceOriginal, tag: "DateTimeOriginal", camera: testCamera},
+			{name: "DNG embedded XMP", hint: ".dng", data: dngFixture(bo, fixtureExif{xmp: xmp}),
+				time: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), source: SourceXmp, tag: "embedded exif:DateTimeOriginal"},
+			{name: "CR3", hint: ".cr3", data: cr3Fixture(bo, testExif, fixtureMovie{creation: time.Date(2021, 5, 6, 5, 0, 0, 0, time.UTC)}),
+				time: original, source: SourceOriginal, tag: "CMT2 DateTimeOriginal", camera: testCamera},
+		}
+		for i := range cases {
+			cases[i].name += " " + byteOrderName(bo)
+		}
+		tests = append(tests, cases...)
+	}
+	var created = time.Date(2021, 5, 6, 7, 0, 0, 0, time.UTC)
+	var modified = time.Date(2021, 5, 6, 7, 5, 0, 0, time.UTC)
+	tests = append(tests,
+		extractTest{name: "MP4", hint: ".mp4", data: mp4Fixture(fixtureMovie{creation: created, modification: modified, make: "GoPro", model: "HERO9 Black"}),
+			time: modified, source: SourceModified, tag: "mvhd modification", camera: Camera{Make: "GoPro", Model: "HERO9 Black"}},
+		extractTest{name: "MP4 prefer original", hint: ".mp4", data: mp4Fixture(fixtureMovie{creation: created, modification: modified}),
+			options: Options{Precedence: [][]string{{SourceOriginal}, {SourceModified}}},
+			time:    created, source: SourceOriginal, tag: "mvhd creation"},
+		extractTest{name: "MP4 creation only", hint: ".mp4", data: mp4Fixture(fixtureMovie{creation: created}),
+			time: created, source: SourceOriginal, tag: "mvhd creation"},
+		extractTest{name: "MP4 GPMF", hint: ".mp4", data: mp4Fixture(fixtureMovie{creation: created, modification: modified, gpsu: "210506050607.000"}),
+			time: time.Date(2021, 5, 6, 5, 6, 7, 0, time.UTC), source: SourceGps, tag: "GPMF GPSU"},
+		extractTest{name: "MP4 broken GPMF", hint: ".mp4", data: mp4Fixture(fixtureMovie{creation: created, modification: modified, gpmf: []byte("DEVC\x00\x01\xff\xff")}),
+			time: modified, source: SourceModified, tag: "mvhd modification"},
+		extractTest{name: "WAV", hint: ".wav", data: wavFixture("2021-05-06", "07:08:09"),
+			time: time.Date(2021, 5, 6, 7, 8, 9, 0, time.UTC), source: SourceOriginal, tag: "bext OriginationDate"},
+		extractTest{name: "MP3", hint: ".mp3", data: mp3Fixture("2021-05-06T07:08:09"),
+			time: time.Date(2021, 5, 6, 7, 8, 9, 0, time.UTC), source: SourceOriginal, tag: "TDRC"},
+		extractTest{name: "file name", hint: "IMG_20210506_070809.jpg", data: jpegFixture(nil, nil),
+			options: Options{Precedence: [][]string{{SourceFilename}}},
+			time:    time.Date(2021, 5, 6, 7, 8, 9, 0, time.UTC), source: SourceFilename, tag: "file name"},
+	)
+	return tests
+}
+
+func TestExtractCreationTime(t *testing.T) {
+	for _, test := range extractTests() {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ExtractCreationTime(bytes.NewReader(test.data), int64(len(test.data)), test.hint, test.options)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !result.Time.Equal(test.time) || result.Source != test.source || result.Tag != test.tag {
+				t.Errorf("got %v %s %q, want %v %s %q", result.Time, result.Source, result.Tag, test.time, test.source, test.tag)
+			}
+			if result.Camera != test.camera {
+				t.Errorf("got camera %+v, want %+v", result.Camera, test.camera)
+			}
+		})
+	}
+}
+
+func TestExtractCreationTimeCandidates(t *testing.T) {
+	for _, bo := range fixtureByteOrders {
+		var data = jpegFixture(tiffFixture(bo, testExif), nil)
+		result, err := ExtractCreationTime(bytes.NewReader(data), int64(len(data)), ".jpg", Options{CollectAll: true})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", byteOrderName(bo), err)
+		}
+		var got []string
+		for _, candidate := range result.Candidates {
+			got = append(got, fmt.Sprintf("%s %s %s %v", candidate.Source, candidate.Tag, candidate.Time.Format(time.RFC3339Nano), candidate.Floating))
+		}
+		var want = []string{
+			"modified DateTime 2021-05-07T10:00:00Z true",
+			"original DateTimeOriginal 2021-05-06T07:08:09.25Z true",
+			"digitized DateTimeDigitized 2021-05-06T07:08:10Z true",
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: got candidates %q, want %q", byteOrderName(bo), got, want)
+		}
+	}
+}
+
+func TestExtractCreationTimeMp4Candidates(t *testing.T) {
+	var created = time.Date(2021, 5, 6, 7, 0, 0, 0, time.UTC)
+	var data = mp4Fixture(fixtureMovie{creation: created, modification: created.Add(5 * time.Minute)})
+	result, err := ExtractCreationTime(bytes.NewReader(data), int64(len(data)), ".mp4", Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, candidate := range result.Candidates {
+		got = append(got, candidate.Tag)
+	}
+	if fmt.Sprint(got) != "[mvhd creation mvhd modification]" {
+		t.Errorf("got candidates %q", got)
+	}
+}
+
+func TestExtractCreationTimeFailure(t *testing.T) {
+	var tests = []struct {
+		name string
+		hint string
+		data []byte
+	}{
+		{"unknown format", ".jpg", []byte("not a photo")},
+		{"JPEG without Exif", ".jpg", jpegFixture(nil, nil)},
+		{"truncated TIFF", ".dng", dngFixture(binary.BigEndian, testExif)[:40]},
+		{"MP4 without moov", ".mp4", quicktimeBox("ftyp", []byte("mp41"))},
+	}
+	for _, test := range tests {
+		_, err := ExtractCreationTime(bytes.NewReader(test.data), int64(len(test.data)), test.hint, Options{})
+		if err == nil {
+			t.Errorf("%s: expected error", test.name)
+			continue
+		}
+		if _, isError := err.(*Error); !isError {
+			t.Errorf("%s: expected *Error, got %T: %v", test.name, err, err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Error describes a failure to process a file.
+type Error struct {
+	// File is empty if failure is not related to a single file.
+	File       string
+	Descriptor string
+	// Err is the underlying error, if any.
+	Err error
+}
+
+func (e *Error) Error() string {
+	var sb strings.Builder
+	if len(e.File) > 0 {
+		sb.WriteString(e.File)
+		sb.WriteString(": ")
+	}
+	sb.WriteString(e.Descriptor)
+	if e.Err != nil {
+		sb.WriteString(": ")
+		sb.WriteString(e.Err.Error())
+	}
+	return sb.String()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// parsers report failures by panicking with *Error,
+// exported functions recover them into returned errors.
+
+func raise(file string, descriptor string) {
+	panic(&Error{File: file, Descriptor: descriptor})
+}
+
+func raiseErr(file string, descriptor string, err error) {
+	panic(&Error{File: file, Descriptor: descriptor, Err: err})
+}
+
+func raiseFmt(format string, a ...interface{}) {
+	raise("", fmt.Sprintf(format, a...))
+}
+
+func raiseFmtFile(file string, format string, a ...interface{}) {
+	raise(file, fmt.Sprintf(format, a...))
+}
+
+func catchFile(err error, file string, descriptor string) {
+	if err != nil {
+		raiseErr(file, descriptor, err)
+	}
+}
+
+// try runs f and returns the failure raised by it, if any,
+// runtime errors caused by malformed input are returned as well.
+func try(f func()) (err error) {
+	defer recoverError(&err)
+	f()
+	return nil
+}
+
+// recoverError is deferred by exported functions to return raised failures as errors.
+func recoverError(err *error) {
+	if r := recover(); r != nil {
+		if e, ok := r.(error); ok {
+			*err = e
+		} else {
+			panic(r)
+		}
+	}
+}
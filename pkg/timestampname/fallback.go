@@ -0,0 +1,64 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type filenamePattern struct {
+	// groups are year, month, day and optional hour, minute, second:
+	pattern *regexp.Regexp
+	// whether the time in the name is UTC rather than local:
+	utc bool
+}
+
+var filenamePatterns = []filenamePattern{
+	// Pixel phones use UTC in names: PXL_20230501_123456789.jpg
+	{regexp.MustCompile(`^PXL_(\d{4})(\d{2})(\d{2})_(\d{2})(\d{2})(\d{2})`), true},
+	// Android cameras: IMG_20230501_123456.jpg, VID_20230501_123456.mp4
+	{regexp.MustCompile(`^(?:IMG|VID|PANO|MVIMG|BURST)_?(\d{4})(\d{2})(\d{2})_(\d{2})(\d{2})(\d{2})`), false},
+	// Android screenshots: Screenshot_20230501-123456.png, Screenshot_2023-05-01-12-34-56.png
+	{regexp.MustCompile(`^Screenshot_(\d{4})-?(\d{2})-?(\d{2})-(\d{2})-?(\d{2})-?(\d{2})`), false},
+	// macOS screenshots: Screenshot 2023-05-01 at 12.34.56.png
+	{regexp.MustCompile(`^Screen ?[Ss]hot (\d{4})-(\d{2})-(\d{2}) at (\d{1,2})\.(\d{2})\.(\d{2})`), false},
+	// WhatsApp has date only: IMG-20230501-WA0001.jpg, VID-20230501-WA0001.mp4
+	{regexp.MustCompile(`^(?:IMG|VID|AUD|PTT)-(\d{4})(\d{2})(\d{2})()()()-WA\d+`), false},
+	// anything else that looks like a timestamp: 20230501_123456, 2023-05-01 12-34-56
+	{regexp.MustCompile(`(\d{4})-?(\d{2})-?(\d{2})[_ T-](\d{2})[-.:]?(\d{2})[-.:]?(\d{2})`), false},
+}
+
+// filenameExtractTimestampCandidates parses timestamp from the well known file name patterns.
+func filenameExtractTimestampCandidates(name string) []Candidate {
+	name = filepath.Base(name)
+	for _, fp := range filenamePatterns {
+		var match = fp.pattern.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+		var clock = match[4] + match[5] + match[6]
+		if len(match[4]) == 1 {
+			clock = "0" + clock
+		}
+		clock += strings.Repeat("0", 6-len(clock))
+		parsed, err := time.Parse("20060102150405", match[1]+match[2]+match[3]+clock)
+		if err != nil {
+			debug("file name '%s' matched '%s' but failed to parse: %v", name, fp.pattern, err)
+			continue
+		}
+		debug("file name '%s' matched '%s'", name, fp.pattern)
+		return []Candidate{{SourceFilename, "file name", parsed, !fp.utc}}
+	}
+	return nil
+}
+
+func mtimeExtractTimestampCandidates(path string) []Candidate {
+	stat, err := os.Stat(path)
+	catchFile(err, path, "failed to stat")
+	return []Candidate{{SourceMtime, "modification time", stat.ModTime(), false}}
+}
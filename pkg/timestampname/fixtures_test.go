@@ -0,0 +1,408 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"time"
+)
+
+// byte orders of TIFF structures, fixtures are built in both:
+var fixtureByteOrders = []binary.ByteOrder{binary.LittleEndian, binary.BigEndian}
+
+// fixtureField is a TIFF field, the value is encoded in byte order of the structure.
+type fixtureField struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value func(bo binary.ByteOrder) []byte
+}
+
+func asciiField(tag uint16, value string) fixtureField {
+	return fixtureField{tag, 2, uint32(len(value) + 1), func(binary.ByteOrder) []byte {
+		return append([]byte(value), 0)
+	}}
+}
+
+func longField(tag uint16, values ...uint32) fixtureField {
+	return fixtureField{tag, 4, uint32(len(values)), func(bo binary.ByteOrder) []byte {
+		var value = make([]byte, 4*len(values))
+		for i, v := range values {
+			bo.PutUint32(value[4*i:], v)
+		}
+		return value
+	}}
+}
+
+// rationalField encodes values with denominator 1000.
+func rationalField(tag uint16, values ...float64) fixtureField {
+	return fixtureField{tag, 5, uint32(len(values)), func(bo binary.ByteOrder) []byte {
+		var value = make([]byte, 8*len(values))
+		for i, v := range values {
+			bo.PutUint32(value[8*i:], uint32(math.Round(v*1000)))
+			bo.PutUint32(value[8*i+4:], 1000)
+		}
+		return value
+	}}
+}
+
+func undefinedField(tag uint16, value []byte) fixtureField {
+	return fixtureField{tag, 7, uint32(len(value)), func(binary.ByteOrder) []byte {
+		return value
+	}}
+}
+
+// tiffWriter builds TIFF structure bottom up: IFDs are appended after the IFDs they point to,
+// so offsets of those are known when writing pointer fields.
+type tiffWriter struct {
+	bo  binary.ByteOrder
+	buf []byte
+}
+
+func newTiffWriter(bo binary.ByteOrder) *tiffWriter {
+	var w = &tiffWriter{bo: bo, buf: make([]byte, 8)}
+	if bo == binary.LittleEndian {
+		copy(w.buf, "II")
+	} else {
+		copy(w.buf, "MM")
+	}
+	bo.PutUint16(w.buf[2:], 42)
+	return w
+}
+
+// ifd appends IFD with the fields followed by values longer than 4 bytes, returns its offset.
+func (w *tiffWriter) ifd(next uint32, fields ...fixtureField) uint32 {
+	if len(w.buf)%2 != 0 {
+		w.buf = append(w.buf, 0)
+	}
+	var offset = uint32(len(w.buf))
+	var entries = make([]byte, 2+12*len(fields)+4)
+	w.bo.PutUint16(entries, uint16(len(fields)))
+	var values []byte
+	var valuesOffset = offset + uint32(len(entries))
+	for i, field := range fields {
+		var entry = entries[2+12*i:]
+		var value = field.value(w.bo)
+		w.bo.PutUint16(entry, field.tag)
+		w.bo.PutUint16(entry[2:], field.typ)
+		w.bo.PutUint32(entry[4:], field.count)
+		if len(value) <= 4 {
+			copy(entry[8:12], value)
+			continue
+		}
+		w.bo.PutUint32(entry[8:], valuesOffset+uint32(len(values)))
+		values = append(values, value...)
+		if len(values)%2 != 0 {
+			values = append(values, 0)
+		}
+	}
+	w.bo.PutUint32(entries[2+12*len(fields):], next)
+	w.buf = append(append(w.buf, entries...), values...)
+	return offset
+}
+
+// bytes returns the structure starting with the IFD at offset.
+func (w *tiffWriter) bytes(first uint32) []byte {
+	w.bo.PutUint32(w.buf[4:], first)
+	return w.buf
+}
+
+// fixtureExif describes metadata of a fixture, empty values are left out.
+type fixtureExif struct {
+	make, model, serial string
+	// Exif dates as 'yyyy:mm:dd hh:mm:ss':
+	dateTime, original, digitized string
+	subSecOriginal                string
+	// GPS date as 'yyyy:mm:dd' and time of the day:
+	gpsDate string
+	gpsTime [3]float64
+	xmp     []byte
+}
+
+// ifd0Fields returns fields of the main image IFD, pointers are added by the caller.
+func (e fixtureExif) ifd0Fields() []fixtureField {
+	var fields []fixtureField
+	if len(e.make) > 0 {
+		fields = append(fields, asciiField(0x010F, e.make))
+	}
+	if len(e.model) > 0 {
+		fields = append(fields, asciiField(0x0110, e.model))
+	}
+	if len(e.dateTime) > 0 {
+		fields = append(fields, asciiField(0x0132, e.dateTime))
+	}
+	if e.xmp != nil {
+		fields = append(fields, undefinedField(0x02BC, e.xmp))
+	}
+	return fields
+}
+
+func (e fixtureExif) exifFields() []fixtureField {
+	var fields []fixtureField
+	if len(e.original) > 0 {
+		fields = append(fields, asciiField(0x9003, e.original))
+	}
+	if len(e.digitized) > 0 {
+		fields = append(fields, asciiField(0x9004, e.digitized))
+	}
+	if len(e.subSecOriginal) > 0 {
+		fields = append(fields, asciiField(0x9291, e.subSecOriginal))
+	}
+	if len(e.serial) > 0 {
+		fields = append(fields, asciiField(0xA431, e.serial))
+	}
+	return fields
+}
+
+// pointerFields writes Exif and GPS IFDs and returns fields pointing to them.
+func (e fixtureExif) pointerFields(w *tiffWriter) []fixtureField {
+	var fields []fixtureField
+	if exifFields := e.exifFields(); len(exifFields) > 0 {
+		fields = append(fields, longField(0x8769, w.ifd(0, exifFields...)))
+	}
+	if len(e.gpsDate) > 0 {
+		var gps = w.ifd(0,
+			rationalField(0x0007, e.gpsTime[:]...),
+			asciiField(0x001D, e.gpsDate))
+		fields = append(fields, longField(0x8825, gps))
+	}
+	return fields
+}
+
+// sortedFields orders fields by tag, as TIFF requires.
+func sortedFields(fields []fixtureField) []fixtureField {
+	var sorted = append([]fixtureField{}, fields...)
+	for i := 1; i < len(sorted); i++ {
+		for j := i; j > 0 && sorted[j].tag < sorted[j-1].tag; j-- {
+			sorted[j], sorted[j-1] = sorted[j-1], sorted[j]
+		}
+	}
+	return sorted
+}
+
+// tiffFixture builds TIFF structure as embedded in JPEG Exif segment:
+// IFD0 pointing to Exif and GPS IFDs, followed by thumbnail IFD1.
+func tiffFixture(bo binary.ByteOrder, e fixtureExif) []byte {
+	var w = newTiffWriter(bo)
+	var ifd1 = w.ifd(0, longField(0x0103, 6))
+	var fields = append(e.ifd0Fields(), e.pointerFields(w)...)
+	return w.bytes(w.ifd(ifd1, sortedFields(fields)...))
+}
+
+// dngFixture builds DNG file: IFD0 with the preview image and SubIFDs with the raw image,
+// the raw image IFD is referenced twice as some converters do.
+func dngFixture(bo binary.ByteOrder, e fixtureExif) []byte {
+	var w = newTiffWriter(bo)
+	var raw = w.ifd(0, longField(0x00FE, 0), longField(0x0100, 4000), longField(0x0101, 3000))
+	var fields = append(e.ifd0Fields(), e.pointerFields(w)...)
+	fields = append(fields,
+		longField(0x00FE, 1),
+		longField(0x014A, raw, raw),
+		undefinedField(0xC612, []byte{1, 4, 0, 0}))
+	return w.bytes(w.ifd(0, sortedFields(fields)...))
+}
+
+// jpegSegmentFixture returns marker segment, length includes itself but not the marker.
+func jpegSegmentFixture(marker byte, body []byte) []byte {
+	var segment = []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(body)+2))
+	return append(segment, body...)
+}
+
+// jpegFixture builds JPEG with JFIF, Exif and XMP segments and a tiny scan, nil segments are left out.
+func jpegFixture(tiff []byte, xmp []byte) []byte {
+	var out = []byte{0xFF, 0xD8}
+	out = append(out, jpegSegmentFixture(0xE0, []byte("JFIF\x00\x01\x02\x00\x00\x01\x00\x01\x00\x00"))...)
+	if tiff != nil {
+		out = append(out, jpegSegmentFixture(0xE1, append([]byte("Exif\x00\x00"), tiff...))...)
+	}
+	if xmp != nil {
+		out = append(out, jpegSegmentFixture(0xE1, append(append([]byte{}, xmpHeaderExpected...), xmp...))...)
+	}
+	return append(out, jpegScanFixture()...)
+}
+
+// jpegScanFixture is a start of scan with entropy coded data containing stuffed and restart markers.
+func jpegScanFixture() []byte {
+	return append(jpegSegmentFixture(0xDA, []byte{1, 1, 0, 0, 63, 0}), 0x12, 0xFF, 0x00, 0x34, 0xFF, 0xD0, 0x56, 0xFF, 0xD9)
+}
+
+// jpegMpfFixture builds JPEG without Exif of the primary image,
+// MPF segment lists the secondary image that has one, as some phones write.
+func jpegMpfFixture(bo binary.ByteOrder, secondary []byte) []byte {
+	var build = func(primarySize uint32, secondaryOffset uint32) []byte {
+		var entries = make([]byte, 32)
+		bo.PutUint32(entries[4:], primarySize)
+		bo.PutUint32(entries[16+4:], uint32(len(secondary)))
+		bo.PutUint32(entries[16+8:], secondaryOffset)
+		var w = newTiffWriter(bo)
+		var mpf = w.bytes(w.ifd(0,
+			undefinedField(0xB000, []byte("0100")),
+			longField(0xB001, 2),
+			undefinedField(0xB002, entries)))
+		var out = []byte{0xFF, 0xD8}
+		out = append(out, jpegSegmentFixture(0xE2, append([]byte("MPF\x00"), mpf...))...)
+		return append(out, jpegScanFixture()...)
+	}
+	var primary = build(0, 0)
+	// MPF TIFF header follows SOI, APP2 marker, length and MPF identifier:
+	var mpfHeader = uint32(2 + 4 + 4)
+	primary = build(uint32(len(primary)), uint32(len(primary))-mpfHeader)
+	return append(primary, secondary...)
+}
+
+// quicktimeBox builds box of the type with the children concatenated as body.
+func quicktimeBox(boxType string, children ...[]byte) []byte {
+	var body = bytes.Join(children, nil)
+	var box = make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(box, uint32(8+len(body)))
+	copy(box[4:], boxType)
+	return append(box, body...)
+}
+
+func uint32Fixture(value uint32) []byte {
+	var out = make([]byte, 4)
+	binary.BigEndian.PutUint32(out, value)
+	return out
+}
+
+func quicktimeUuidBox(uuid string, children ...[]byte) []byte {
+	var id, err = hex.DecodeString(uuid)
+	if err != nil {
+		panic(err)
+	}
+	return quicktimeBox("uuid", append([][]byte{id}, children...)...)
+}
+
+// quicktimeSeconds returns seconds since 1904, zero time gives zero.
+func quicktimeSeconds(t time.Time) uint32 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint32(t.Unix() + int64(quicktimeEpochOffset))
+}
+
+// quicktimeHeaderBox builds version 0 mvhd, tkhd or mdhd box, only creation and modification times are set.
+func quicktimeHeaderBox(boxType string, creation time.Time, modification time.Time, length int) []byte {
+	var body = make([]byte, length)
+	binary.BigEndian.PutUint32(body[4:], quicktimeSeconds(creation))
+	binary.BigEndian.PutUint32(body[8:], quicktimeSeconds(modification))
+	return quicktimeBox(boxType, body)
+}
+
+func quicktimeTextBox(boxType string, text string) []byte {
+	var body = make([]byte, 4)
+	binary.BigEndian.PutUint16(body, uint16(len(text)))
+	return quicktimeBox(boxType, body, []byte(text))
+}
+
+// fixtureMovie describes QuickTime fixture, empty values are left out.
+type fixtureMovie struct {
+	creation, modification time.Time
+	// gpsu is GoPro GPS time as 'yymmddhhmmss.sss', gpmf replaces the whole GPMF sample if not nil:
+	gpsu        string
+	gpmf        []byte
+	make, model string
+}
+
+// gpmfFixture builds GPMF sample with GPS stream reporting the time.
+func gpmfFixture(gpsu string) []byte {
+	var klv = func(key string, typ byte, size byte, repeat uint16, value []byte) []byte {
+		var entry = []byte(key)
+		entry = append(entry, typ, size, byte(repeat>>8), byte(repeat))
+		entry = append(entry, value...)
+		for len(entry)%4 != 0 {
+			entry = append(entry, 0)
+		}
+		return entry
+	}
+	var stream = append(klv("GPSF", 'L', 4, 1, []byte{0, 0, 0, 3}), klv("GPSU", 'U', 16, 1, []byte(gpsu))...)
+	var device = klv("STRM", 0, 1, uint16(len(stream)), stream)
+	return klv("DEVC", 0, 1, uint16(len(device)), device)
+}
+
+// mp4Fixture builds MP4 with GPMF sample in mdat placed before moov, so the sample offset is known.
+func mp4Fixture(m fixtureMovie) []byte {
+	var ftyp = quicktimeBox("ftyp", []byte("mp41\x00\x00\x00\x00mp41isom"))
+	var sample = m.gpmf
+	if sample == nil && len(m.gpsu) > 0 {
+		sample = gpmfFixture(m.gpsu)
+	}
+	var mdat = quicktimeBox("mdat", sample)
+	var moov = [][]byte{quicktimeHeaderBox("mvhd", m.creation, m.modification, 100)}
+	if sample != nil {
+		var stsd = quicktimeBox("stsd", []byte{0, 0, 0, 0, 0, 0, 0, 1}, quicktimeBox("gpmd", make([]byte, 8)))
+		var stco = quicktimeBox("stco", []byte{0, 0, 0, 0, 0, 0, 0, 1}, uint32Fixture(uint32(len(ftyp)+8)))
+		var stsz = quicktimeBox("stsz", []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}, uint32Fixture(uint32(len(sample))))
+		var mdia = quicktimeBox("mdia",
+			quicktimeHeaderBox("mdhd", m.creation, m.modification, 24),
+			quicktimeBox("minf", quicktimeBox("stbl", stsd, stco, stsz)))
+		moov = append(moov, quicktimeBox("trak", quicktimeHeaderBox("tkhd", m.creation, m.modification, 84), mdia))
+	}
+	if len(m.make) > 0 || len(m.model) > 0 {
+		moov = append(moov, quicktimeBox("udta", quicktimeTextBox("\xa9mak", m.make), quicktimeTextBox("\xa9mod", m.model)))
+	}
+	return bytes.Join([][]byte{ftyp, mdat, quicktimeBox("moov", moov...)}, nil)
+}
+
+// cr3Fixture builds CR3 with IFD0 in CMT1 and Exif IFD in CMT2 boxes, both are TIFF structures.
+func cr3Fixture(bo binary.ByteOrder, e fixtureExif, m fixtureMovie) []byte {
+	var cmt1 = newTiffWriter(bo)
+	var cmt2 = newTiffWriter(bo)
+	var canon = quicktimeUuidBox("85c0b687820f11e08111f4ce462b6a48",
+		quicktimeBox("CNCV", []byte("CanonCR3_001/01.09.00/00.00.00")),
+		quicktimeBox("CMT1", cmt1.bytes(cmt1.ifd(0, e.ifd0Fields()...))),
+		quicktimeBox("CMT2", cmt2.bytes(cmt2.ifd(0, e.exifFields()...))))
+	return bytes.Join([][]byte{
+		quicktimeBox("ftyp", []byte("crx \x00\x00\x00\x01crx isom")),
+		quicktimeBox("moov", canon, quicktimeHeaderBox("mvhd", m.creation, m.modification, 100)),
+		quicktimeBox("mdat", make([]byte, 16)),
+	}, nil)
+}
+
+// wavFixture builds WAV with bext chunk of the origination date and time.
+func wavFixture(date string, clock string) []byte {
+	var bext = make([]byte, bextOriginationOffset+bextOriginationLength+8)
+	copy(bext[bextOriginationOffset:], date+clock)
+	var chunk = func(id string, body []byte) []byte {
+		var out = append([]byte(id), 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(out[4:], uint32(len(body)))
+		out = append(out, body...)
+		if len(body)%2 != 0 {
+			out = append(out, 0)
+		}
+		return out
+	}
+	var body = append([]byte("WAVE"), chunk("fmt ", make([]byte, 16))...)
+	body = append(body, chunk("bext", bext)...)
+	body = append(body, chunk("data", []byte{0, 0, 0})...)
+	var out = append([]byte("RIFF"), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(body)))
+	return append(out, body...)
+}
+
+// mp3Fixture builds ID3v2.4 tag with TDRC frame followed by a frame header.
+func mp3Fixture(recorded string) []byte {
+	var synchsafe = func(value int) []byte {
+		return []byte{byte(value >> 21 & 0x7F), byte(value >> 14 & 0x7F), byte(value >> 7 & 0x7F), byte(value & 0x7F)}
+	}
+	var text = append([]byte{3}, recorded...)
+	var frame = append(append([]byte("TDRC"), synchsafe(len(text))...), 0, 0)
+	frame = append(frame, text...)
+	var tag = append([]byte{'I', 'D', '3', 4, 0, 0}, synchsafe(len(frame)+16)...)
+	tag = append(tag, frame...)
+	tag = append(tag, make([]byte, 16)...)
+	return append(tag, 0xFF, 0xFB, 0x90, 0x00)
+}
+
+// xmpFixture builds XMP packet with the date as attribute.
+func xmpFixture(property string, date string) []byte {
+	return []byte(`<?xpacket begin="" id="W5M0MpCehiHzreSzNTczkc9d"?>` +
+		`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+		`<rdf:Description ` + property + `="` + date + `"/>` +
+		`</rdf:RDF></x:xmpmeta><?xpacket end="w"?>`)
+}
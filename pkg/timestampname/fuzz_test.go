@@ -0,0 +1,118 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func builtinByName(t testing.TB, name string) *builtinExtractor {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	for _, e := range registry {
+		if builtin, isBuiltin := e.(*builtinExtractor); isBuiltin && builtin.name == name {
+			return builtin
+		}
+	}
+	t.Fatalf("no builtin extractor %s", name)
+	return nil
+}
+
+// checkFuzzError fails on panics other than raised failures, malformed input must end with an error.
+func checkFuzzError(t *testing.T, step string, err error) {
+	var runtimeErr runtime.Error
+	if errors.As(err, &runtimeErr) {
+		t.Fatalf("%s: %v", step, err)
+	}
+}
+
+// fuzzExtractor runs every parser entry point of the extractor on the data.
+func fuzzExtractor(f *testing.F, name string, hint string, seeds ...[]byte) {
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var e = builtinByName(t, name)
+		var in = func() reader {
+			return newReaderAt(bytes.NewReader(data), int64(len(data)), "fuzz"+hint)
+		}
+		checkFuzzError(t, "extract", try(func() { e.extract(in()) }))
+		if e.searchXmp != nil {
+			checkFuzzError(t, "searchXmp", try(func() { e.searchXmp(in()) }))
+		}
+		if e.shift != nil {
+			checkFuzzError(t, "shift", try(func() { e.shift(in(), time.Hour) }))
+		}
+		if e.camera != nil {
+			checkFuzzError(t, "camera", try(func() { e.camera(in()) }))
+		}
+		_, err := ExtractCreationTime(bytes.NewReader(data), int64(len(data)), hint, Options{CollectAll: true})
+		checkFuzzError(t, "ExtractCreationTime", err)
+	})
+}
+
+func FuzzJpeg(f *testing.F) {
+	var seeds [][]byte
+	for _, bo := range fixtureByteOrders {
+		seeds = append(seeds,
+			jpegFixture(tiffFixture(bo, testExif), xmpFixture("exif:DateTimeOriginal", "2020-01-02T03:04:05")),
+			jpegMpfFixture(bo, jpegFixture(tiffFixture(bo, testExif), nil)))
+	}
+	fuzzExtractor(f, "JPEG", ".jpg", seeds...)
+}
+
+func FuzzTiff(f *testing.F) {
+	var seeds [][]byte
+	for _, bo := range fixtureByteOrders {
+		var exif = testExif
+		exif.gpsDate = "2021:05:06"
+		exif.gpsTime = [3]float64{5, 8, 7.5}
+		seeds = append(seeds, tiffFixture(bo, exif), dngFixture(bo, testExif))
+	}
+	fuzzExtractor(f, "TIFF", ".dng", seeds...)
+}
+
+func FuzzMp4(f *testing.F) {
+	var created = time.Date(2021, 5, 6, 7, 0, 0, 0, time.UTC)
+	fuzzExtractor(f, "MP4", ".mp4",
+		mp4Fixture(fixtureMovie{creation: created, modification: created.Add(time.Minute), make: "GoPro", model: "HERO9 Black"}),
+		mp4Fixture(fixtureMovie{creation: created, gpsu: "210506050607.000"}))
+}
+
+func FuzzCr3(f *testing.F) {
+	var seeds [][]byte
+	for _, bo := range fixtureByteOrders {
+		seeds = append(seeds, cr3Fixture(bo, testExif, fixtureMovie{creation: time.Date(2021, 5, 6, 5, 0, 0, 0, time.UTC)}))
+	}
+	fuzzExtractor(f, "CR3", ".cr3", seeds...)
+}
+
+func FuzzWav(f *testing.F) {
+	fuzzExtractor(f, "WAV", ".wav", wavFixture("2021-05-06", "07:08:09"))
+}
+
+func FuzzMp3(f *testing.F) {
+	fuzzExtractor(f, "MP3", ".mp3", mp3Fixture("2021-05-06T07:08:09"))
+}
+
+func FuzzXmpPacket(f *testing.F) {
+	f.Add(xmpFixture("exif:DateTimeOriginal", "2020-01-02T03:04:05+02:00"))
+	f.Add(xmpFixture("xmp:CreateDate", "2020-01-02T03:04:05.25"))
+	f.Fuzz(func(t *testing.T, packet []byte) {
+		checkFuzzError(t, "xmpParsePacket", try(func() { xmpParsePacket(packet, "fuzz") }))
+	})
+}
+
+func FuzzFilename(f *testing.F) {
+	f.Add("IMG_20210506_070809.jpg")
+	f.Add("20180430_184327(0).jpg")
+	f.Add("1-20210506-070809-Canon-EOS-R5.jpg")
+	f.Fuzz(func(t *testing.T, name string) {
+		checkFuzzError(t, "filename", try(func() { filenameExtractTimestampCandidates(name) }))
+	})
+}
@@ -0,0 +1,175 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// following resources were used to implement this parser:
+// https://github.com/gopro/gpmf-parser/blob/main/docs/README.md
+
+const (
+	gpmfTypeNested byte = 0
+	gpmfTypeUtc    byte = 'U'
+)
+
+// gpmfExtractGpsTimestamp looks up the GoPro metadata track
+// and returns the first GPS UTC time recorded in it.
+func gpmfExtractGpsTimestamp(in reader, moovIn reader) (time.Time, bool) {
+	for _, trakIn := range quicktimeSearchBoxes(moovIn, "trak") {
+		stblIn, err := quicktimeSearchBoxPath(trakIn, "mdia", "minf", "stbl")
+		if err != nil {
+			continue
+		}
+		if !gpmfIsMetadataTrack(stblIn) {
+			continue
+		}
+		debug("GPMF found gpmd track")
+		payload := gpmfFirstSample(in, stblIn)
+		if payload == nil {
+			continue
+		}
+		var gpsu, found = gpmfSearchGpsu(payload, 0)
+		if !found {
+			continue
+		}
+		parsed, parseErr := time.Parse("060102150405.000", gpsu)
+		if parseErr != nil {
+			debug("GPMF failed to parse GPSU value '%s': %v", gpsu, parseErr)
+			continue
+		}
+		debug("GPMF GPS time: %v", parsed)
+		return parsed, true
+	}
+	return time.Time{}, false
+}
+
+func gpmfIsMetadataTrack(stblIn reader) bool {
+	stsdIn, err := quicktimeSearchBox(stblIn, "stsd")
+	if err != nil {
+		return false
+	}
+	// 1 byte version, 3 bytes flags, 4 bytes entry count,
+	// followed by sample entries, each starts with 4 bytes size and 4 bytes format:
+	var header = make([]byte, 16)
+	_, err = io.ReadFull(stsdIn, header)
+	if err != nil {
+		return false
+	}
+	return string(header[12:16]) == "gpmd"
+}
+
+// gpmfFirstSample locates the first sample of the track in the file,
+// sample table offsets are absolute, so the top level reader is required.
+func gpmfFirstSample(in reader, stblIn reader) reader {
+	var chunkOffset int64
+	if stcoIn, err := quicktimeSearchBox(stblIn, "stco"); err == nil {
+		// 1 byte version, 3 bytes flags, 4 bytes entry count, 4 bytes per offset:
+		var header [3]uint32
+		err = binary.Read(stcoIn, binary.BigEndian, &header)
+		catchFile(err, in.Name(), "failed to read stco box")
+		if header[1] == 0 {
+			return nil
+		}
+		chunkOffset = int64(header[2])
+	} else if co64In, err := quicktimeSearchBox(stblIn, "co64"); err == nil {
+		// 1 byte version, 3 bytes flags, 4 bytes entry count, 8 bytes per offset:
+		var header [2]uint32
+		err = binary.Read(co64In, binary.BigEndian, &header)
+		catchFile(err, in.Name(), "failed to read co64 box")
+		if header[1] == 0 {
+			return nil
+		}
+		var offset uint64
+		err = binary.Read(co64In, binary.BigEndian, &offset)
+		catchFile(err, in.Name(), "failed to read co64 box")
+		chunkOffset = int64(offset)
+	} else {
+		return nil
+	}
+
+	stszIn, err := quicktimeSearchBox(stblIn, "stsz")
+	if err != nil {
+		return nil
+	}
+	// 1 byte version, 3 bytes flags, 4 bytes sample size, 4 bytes sample count,
+	// if sample size is 0 then table of sizes follows:
+	var header [3]uint32
+	err = binary.Read(stszIn, binary.BigEndian, &header)
+	catchFile(err, in.Name(), "failed to read stsz box")
+	var sampleSize = header[1]
+	if sampleSize == 0 {
+		if header[2] == 0 {
+			return nil
+		}
+		err = binary.Read(stszIn, binary.BigEndian, &sampleSize)
+		catchFile(err, in.Name(), "failed to read stsz box")
+	}
+	debug("GPMF first sample at offset: %d, with length: %d", chunkOffset, sampleSize)
+	if chunkOffset+int64(sampleSize) > in.Size() {
+		raise(in.Name(), "GPMF sample goes over file length")
+	}
+	return newReader(in, chunkOffset, int64(sampleSize))
+}
+
+// gpmfMaxDepth limits nesting of GPMF structures.
+const gpmfMaxDepth = 8
+
+// gpmfSearchGpsu walks KLV structure of the GPMF payload,
+// GPSU value is skipped if stream reports no GPS fix.
+func gpmfSearchGpsu(in reader, depth int) (string, bool) {
+	if depth > gpmfMaxDepth {
+		raise(in.Name(), "GPMF nesting too deep")
+	}
+	var offset int64
+	var gpsu string
+	var gpsFix = true
+	for offset+8 <= in.Size() {
+		_, err := in.Seek(offset, 0)
+		catchFile(err, in.Name(), "failed to seek GPMF entry")
+		// 4 bytes key, 1 byte type, 1 byte structure size, 2 bytes repeat:
+		var key = make([]byte, 4)
+		_, err = io.ReadFull(in, key)
+		catchFile(err, in.Name(), "failed to read GPMF key")
+		var typeAndSize = make([]byte, 2)
+		_, err = io.ReadFull(in, typeAndSize)
+		catchFile(err, in.Name(), "failed to read GPMF type")
+		var repeat uint16
+		err = binary.Read(in, binary.BigEndian, &repeat)
+		catchFile(err, in.Name(), "failed to read GPMF repeat")
+
+		var length = int64(typeAndSize[1]) * int64(repeat)
+		// values are padded to 4 bytes:
+		var paddedLength = (length + 3) &^ 3
+		if offset+8+length > in.Size() {
+			raise(in.Name(), "GPMF entry goes over sample length")
+		}
+
+		switch {
+		case typeAndSize[0] == gpmfTypeNested:
+			if value, found := gpmfSearchGpsu(newReader(in, offset+8, length), depth+1); found {
+				return value, true
+			}
+		case string(key) == "GPSF" && length >= 4:
+			var fix uint32
+			err = binary.Read(in, binary.BigEndian, &fix)
+			catchFile(err, in.Name(), "failed to read GPSF value")
+			gpsFix = fix != 0
+		case string(key) == "GPSU" && typeAndSize[0] == gpmfTypeUtc && length >= 16 && len(gpsu) == 0:
+			var value = make([]byte, 16)
+			_, err = io.ReadFull(in, value)
+			catchFile(err, in.Name(), "failed to read GPSU value")
+			gpsu = string(value)
+			debug("GPMF GPSU value: %s", gpsu)
+		}
+		offset += 8 + paddedLength
+	}
+	if len(gpsu) > 0 && gpsFix {
+		return gpsu, true
+	}
+	return "", false
+}
@@ -0,0 +1,254 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+)
+
+// following resources were used to implement this parser:
+// https://www.media.mit.edu/pia/Research/deepview/exif.html
+// https://www.fileformat.info/format/jpeg/egff.htm
+// http://vip.sugovica.hu/Sardi/kepnezo/JPEG%20File%20Layout%20and%20Format.htm
+
+const (
+	jpegSoiExpected          uint16 = 0xFFD8
+	exifHeaderSuffixExpected uint16 = 0x0000
+)
+
+// JPEG markers, the second byte after 0xFF:
+const (
+	jpegTem  byte = 0x01
+	jpegRst0 byte = 0xD0
+	jpegRst7 byte = 0xD7
+	jpegSoi  byte = 0xD8
+	jpegEoi  byte = 0xD9
+	jpegSos  byte = 0xDA
+	jpegApp1 byte = 0xE1
+	jpegApp2 byte = 0xE2
+)
+
+var (
+	exifHeaderExpected = binary.BigEndian.Uint32([]byte("Exif"))
+	xmpHeaderExpected  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	mpfHeaderExpected  = []byte("MPF\x00")
+)
+
+func init() {
+	Register(&builtinExtractor{
+		name:       "JPEG",
+		extensions: []string{".jpg", ".jpeg"},
+		match: func(header []byte) bool {
+			return len(header) >= 3 && header[0] == 0xFF && header[1] == 0xD8 && header[2] == 0xFF
+		},
+		extract:   jpegExtractTimestampCandidates,
+		searchXmp: jpegSearchXmp,
+		shift: func(in reader, shift time.Duration) []Patch {
+			return tiffShiftTimestamps(jpegSearchExif(in), shift)
+		},
+		camera: func(in reader) Camera {
+			return tiffCamera(jpegSearchExif(in))
+		},
+	})
+}
+
+func jpegExtractTimestampCandidates(in reader) []Candidate {
+	return tiffExtractTimestampCandidates(jpegSearchExif(in))
+}
+
+// jpegSegment is a marker segment, offset and length are of the segment body.
+type jpegSegment struct {
+	marker byte
+	offset int64
+	length int64
+}
+
+// jpegWalkSegments calls visit for marker segments before the image data until visit returns false.
+// Fill bytes are skipped, standalone markers are ignored, walking stops at SOS or EOI.
+func jpegWalkSegments(in reader, visit func(segment jpegSegment) bool) {
+	var header = make([]byte, 2)
+	_, err := in.ReadAt(header, 0)
+	catchFile(err, in.Name(), "failed to read JPEG header")
+	if binary.BigEndian.Uint16(header) != jpegSoiExpected {
+		raise(in.Name(), "unexpected header")
+	}
+	var offset int64 = 2 // 2 bytes SOI
+	for offset < in.Size() {
+		_, err = in.ReadAt(header[:1], offset)
+		catchFile(err, in.Name(), "failed to read JPEG marker")
+		if header[0] != 0xFF {
+			raiseFmtFile(in.Name(), "expected JPEG marker at offset: %d", offset)
+		}
+		// any number of 0xFF fill bytes may precede the marker:
+		for header[0] == 0xFF {
+			offset++
+			if offset >= in.Size() {
+				return
+			}
+			_, err = in.ReadAt(header[:1], offset)
+			catchFile(err, in.Name(), "failed to read JPEG marker")
+		}
+		var marker = header[0]
+		offset++
+		switch {
+		case marker == jpegSos, marker == jpegEoi:
+			return
+		case marker == 0x00:
+			raiseFmtFile(in.Name(), "invalid JPEG marker at offset: %d", offset-1)
+		case marker == jpegTem, marker >= jpegRst0 && marker <= jpegRst7, marker == jpegSoi:
+			// standalone markers have no length:
+			continue
+		}
+		if offset+2 > in.Size() {
+			raise(in.Name(), "JPEG field goes over file length")
+		}
+		_, err = in.ReadAt(header, offset)
+		catchFile(err, in.Name(), "failed to read JPEG field length")
+		var fieldLength = int64(binary.BigEndian.Uint16(header))
+		// field length includes itself:
+		if fieldLength < 2 || offset+fieldLength > in.Size() {
+			raise(in.Name(), "JPEG field goes over file length")
+		}
+		if !visit(jpegSegment{marker, offset + 2, fieldLength - 2}) {
+			return
+		}
+		offset += fieldLength
+	}
+}
+
+// jpegSearchExif returns reader of TIFF structure in Exif APP1 field,
+// Exif of the secondary images in MPF APP2 field is used if the primary image has none.
+func jpegSearchExif(in reader) reader {
+	if exif := _jpegSearchExifSegment(in); exif != nil {
+		return exif
+	}
+	if exif := _jpegSearchMpfExif(in); exif != nil {
+		return exif
+	}
+	raise(in.Name(), "no Exif APP1 field found")
+	return nil
+}
+
+// _jpegSearchExifSegment returns reader of TIFF structure in the first APP1 field with Exif header, or nil.
+func _jpegSearchExifSegment(in reader) reader {
+	var exif reader
+	jpegWalkSegments(in, func(segment jpegSegment) bool {
+		// 4 bytes Exif, 2 bytes suffix:
+		if segment.marker != jpegApp1 || segment.length < 6 {
+			return true
+		}
+		var exifHeader = make([]byte, 6)
+		_, err := in.ReadAt(exifHeader, segment.offset)
+		catchFile(err, in.Name(), "failed to read Exif header")
+		if binary.BigEndian.Uint32(exifHeader) != exifHeaderExpected ||
+			binary.BigEndian.Uint16(exifHeader[4:]) != exifHeaderSuffixExpected {
+			debug("JPEG skipping APP1 field without Exif header at offset: %d", segment.offset)
+			return true
+		}
+		// body after the header is a valid TIFF:
+		exif = newReader(in, segment.offset+6, segment.length-6)
+		return false
+	})
+	return exif
+}
+
+// _jpegSearchMpfExif returns reader of TIFF structure in Exif of the first secondary image
+// listed in MPF APP2 field that has one, or nil.
+// https://www.cipa.jp/std/documents/e/DC-007_E.pdf
+func _jpegSearchMpfExif(in reader) reader {
+	var mpf reader
+	jpegWalkSegments(in, func(segment jpegSegment) bool {
+		if segment.marker != jpegApp2 || segment.length < int64(len(mpfHeaderExpected)) {
+			return true
+		}
+		var mpfHeader = make([]byte, len(mpfHeaderExpected))
+		_, err := in.ReadAt(mpfHeader, segment.offset)
+		catchFile(err, in.Name(), "failed to read MPF header")
+		if !bytes.Equal(mpfHeader, mpfHeaderExpected) {
+			return true
+		}
+		mpf = newReader(in, segment.offset+int64(len(mpfHeader)), segment.length-int64(len(mpfHeader)))
+		return false
+	})
+	if mpf == nil {
+		return nil
+	}
+	debug("JPEG MPF field found at offset: %d", mpf.Origin())
+	// image offsets are relative to the MPF TIFF header:
+	var mpfOffset = mpf.Origin() - in.Origin()
+	for _, entry := range _jpegReadMpEntries(mpf) {
+		// offset of the primary image is zero:
+		if entry.Offset == 0 {
+			continue
+		}
+		if mpfOffset+int64(entry.Offset)+int64(entry.Size) > in.Size() {
+			raise(in.Name(), "MPF image goes over file length")
+		}
+		var image = newReader(in, mpfOffset+int64(entry.Offset), int64(entry.Size))
+		var exif reader
+		err := try(func() {
+			exif = _jpegSearchExifSegment(image)
+		})
+		if err != nil {
+			debug("JPEG MPF image at offset %d failed: %v", image.Origin(), err)
+			continue
+		}
+		if exif != nil {
+			debug("JPEG using Exif of MPF image at offset: %d", image.Origin())
+			return exif
+		}
+	}
+	return nil
+}
+
+// jpegMpEntry is an entry of MPF MPEntry tag.
+type jpegMpEntry struct {
+	Attribute  uint32
+	Size       uint32
+	Offset     uint32
+	Dependent1 uint16
+	Dependent2 uint16
+}
+
+// _jpegReadMpEntries reads MPEntry tag of the MP Index IFD.
+func _jpegReadMpEntries(in reader) []jpegMpEntry {
+	var bo, ifdOffset = _tiffReadHeader(in)
+	ifd, _ := _tiffReadIfd(in, bo, "MPIndex", int64(ifdOffset))
+	// 0xB002: MPEntry, UNDEFINED type, 16 bytes per image:
+	entry, found := ifd.entry(0xB002)
+	if !found {
+		return nil
+	}
+	if entry.typ != 7 || entry.count%16 != 0 {
+		raise(in.Name(), "invalid MPF MPEntry tag")
+	}
+	// value is read first, it is checked against file length:
+	var value = entry.bytes()
+	var entries = make([]jpegMpEntry, len(value)/16)
+	err := binary.Read(bytes.NewReader(value), bo, entries)
+	catchFile(err, in.Name(), "failed to read MPF MPEntry value")
+	return entries
+}
+
+// jpegSearchXmp returns XMP packet from APP1 field, or nil if there is none.
+func jpegSearchXmp(in reader) []byte {
+	var packet []byte
+	jpegWalkSegments(in, func(segment jpegSegment) bool {
+		if segment.marker != jpegApp1 || segment.length <= int64(len(xmpHeaderExpected)) {
+			return true
+		}
+		var body = make([]byte, segment.length)
+		_, err := in.ReadAt(body, segment.offset)
+		catchFile(err, in.Name(), "failed to read JPEG APP1 field")
+		if !bytes.HasPrefix(body, xmpHeaderExpected) {
+			return true
+		}
+		debug("JPEG XMP packet found at offset: %d", segment.offset)
+		packet = body[len(xmpHeaderExpected):]
+		return false
+	})
+	return packet
+}
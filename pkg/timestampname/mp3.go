@@ -0,0 +1,186 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// following resources were used to implement this parser:
+// https://id3.org/id3v2.4.0-structure
+// https://id3.org/id3v2.4.0-frames
+// https://id3.org/id3v2.3.0
+
+const (
+	id3FlagExtendedHeader byte = 0x40
+)
+
+var (
+	// ID3v2.4 timestamps are subset of ISO 8601, precision may vary:
+	id3TimestampLayouts = []string{
+		"2006-01-02T15:04:05",
+		"2006-01-02T15:04",
+		"2006-01-02T15",
+		"2006-01-02",
+	}
+)
+
+func init() {
+	Register(&builtinExtractor{
+		name:       "MP3",
+		extensions: []string{".mp3"},
+		match: func(header []byte) bool {
+			return bytes.HasPrefix(header, []byte("ID3"))
+		},
+		extract: mp3ExtractTimestampCandidates,
+	})
+}
+
+// id3SynchsafeInt decodes integer where most significant bit of every byte is zeroed.
+func id3SynchsafeInt(b []byte) int64 {
+	var value int64
+	for _, v := range b {
+		value = value<<7 | int64(v&0x7F)
+	}
+	return value
+}
+
+func id3PlainInt(b []byte) int64 {
+	var value int64
+	for _, v := range b {
+		value = value<<8 | int64(v)
+	}
+	return value
+}
+
+func mp3ExtractTimestampCandidates(in reader) []Candidate {
+	// 3 bytes "ID3", 1 byte major version, 1 byte revision, 1 byte flags, 4 bytes synchsafe size:
+	var header = make([]byte, 10)
+	_, err := io.ReadFull(in, header)
+	catchFile(err, in.Name(), "failed to read ID3 header")
+	if string(header[0:3]) != "ID3" {
+		raise(in.Name(), "no ID3v2 tag found")
+	}
+	var version = header[3]
+	if version != 3 && version != 4 {
+		raiseFmtFile(in.Name(), "unsupported ID3v2 version: %d", version)
+	}
+	var tagEnd = 10 + id3SynchsafeInt(header[6:10])
+	if tagEnd > in.Size() {
+		raise(in.Name(), "ID3 tag goes over file length")
+	}
+	debug("ID3 version: 2.%d, tag length: %d", version, tagEnd)
+
+	var offset int64 = 10
+	if header[5]&id3FlagExtendedHeader != 0 {
+		var extendedSize = make([]byte, 4)
+		_, err = io.ReadFull(in, extendedSize)
+		catchFile(err, in.Name(), "failed to read ID3 extended header")
+		if version == 4 {
+			// size includes itself:
+			offset += id3SynchsafeInt(extendedSize)
+		} else {
+			offset += 4 + id3PlainInt(extendedSize)
+		}
+	}
+
+	var frames = make(map[string]string)
+	// 4 bytes frame id, 4 bytes size, 2 bytes flags:
+	var frameHeader = make([]byte, 10)
+	for offset+10 <= tagEnd {
+		_, err = in.Seek(offset, 0)
+		catchFile(err, in.Name(), "failed to seek till next ID3 frame")
+		_, err = io.ReadFull(in, frameHeader)
+		catchFile(err, in.Name(), "failed to read ID3 frame header")
+		if frameHeader[0] == 0 {
+			break // reached the padding
+		}
+		var frameId = string(frameHeader[0:4])
+		var frameSize int64
+		if version == 4 {
+			frameSize = id3SynchsafeInt(frameHeader[4:8])
+		} else {
+			frameSize = id3PlainInt(frameHeader[4:8])
+		}
+		debug("ID3 encountered frame '%s' at offset %d, with length %d", frameId, offset, frameSize)
+		if offset+10+frameSize > tagEnd {
+			raise(in.Name(), "ID3 frame goes over tag length")
+		}
+		if (frameId == "TDRC" || frameId == "TDOR") && frameSize > 1 {
+			var body = make([]byte, frameSize)
+			_, err = io.ReadFull(in, body)
+			catchFile(err, in.Name(), "failed to read ID3 frame")
+			frames[frameId] = id3DecodeText(body[0], body[1:])
+			debug("ID3 frame '%s' value: %s", frameId, frames[frameId])
+		}
+		offset += 10 + frameSize
+	}
+
+	var candidates []Candidate
+	for _, frameId := range []string{"TDRC", "TDOR"} {
+		var value, found = frames[frameId]
+		if !found {
+			continue
+		}
+		var parsed, valid = id3ParseTimestamp(value)
+		if !valid {
+			debug("ID3 failed to parse frame '%s' value: %s", frameId, value)
+			continue
+		}
+		candidates = append(candidates, Candidate{SourceOriginal, frameId, parsed, true})
+	}
+	return candidates
+}
+
+func id3ParseTimestamp(value string) (time.Time, bool) {
+	for _, layout := range id3TimestampLayouts {
+		if parsed, parseErr := time.Parse(layout, value); parseErr == nil {
+			return parsed, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// id3DecodeText decodes text frame value according to its encoding byte:
+// 0 is ISO-8859-1, 1 is UTF-16 with BOM, 2 is UTF-16BE, 3 is UTF-8.
+func id3DecodeText(encoding byte, b []byte) string {
+	var text string
+	switch encoding {
+	case 1, 2:
+		var bigEndian = encoding == 2
+		if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+			bigEndian = true
+			b = b[2:]
+		} else if len(b) >= 2 && b[0] == 0xFF && b[1] == 0xFE {
+			bigEndian = false
+			b = b[2:]
+		}
+		var units = make([]uint16, len(b)/2)
+		for i := range units {
+			if bigEndian {
+				units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+			} else {
+				units[i] = uint16(b[2*i+1])<<8 | uint16(b[2*i])
+			}
+		}
+		text = string(utf16.Decode(units))
+	case 0:
+		var runes = make([]rune, len(b))
+		for i, v := range b {
+			runes[i] = rune(v)
+		}
+		text = string(runes)
+	default:
+		text = string(b)
+	}
+	// values may be terminated or separated with null characters:
+	if index := strings.IndexRune(text, 0); index >= 0 {
+		text = text[:index]
+	}
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,150 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+var (
+	quicktimeEpochOffset = uint32(-time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC).Unix())
+)
+
+func init() {
+	Register(&builtinExtractor{
+		name:       "MP4",
+		extensions: []string{".mp4", ".m4a"},
+		match:      quicktimeMatch,
+		extract:    mp4ExtractTimestampCandidates,
+		searchXmp:  quicktimeSearchXmp,
+		shift:      quicktimeShiftTimestamps,
+		camera:     quicktimeCamera,
+	})
+}
+
+func mp4ExtractTimestampCandidates(in reader) []Candidate {
+	var candidates []Candidate
+	moovIn, err := quicktimeSearchBox(in, "moov")
+	catchFile(err, in.Name(), "moov box not found")
+	// GoPro cameras often have clock wrong, but GPS time is reliable,
+	// broken GPMF track falls back to mvhd times:
+	err = try(func() {
+		if gpsTime, found := gpmfExtractGpsTimestamp(in, moovIn); found {
+			candidates = append(candidates, Candidate{SourceGps, "GPMF GPSU", gpsTime, false})
+		}
+	})
+	if err != nil {
+		debug("failed to extract GPMF GPS time: %v", err)
+	}
+	mvhdIn, err := quicktimeSearchBox(moovIn, "mvhd")
+	catchFile(err, in.Name(), "mvhd box not found")
+	var versionBytes = make([]byte, 1)
+	_, err = io.ReadFull(mvhdIn, versionBytes)
+	catchFile(err, in.Name(), "failed to read mvhd version")
+	var version = versionBytes[0]
+	if version > 1 {
+		raise(in.Name(), "unsupported mvhd version")
+	}
+	var flagBytes = make([]byte, 3)
+	_, err = io.ReadFull(mvhdIn, flagBytes)
+	catchFile(err, in.Name(), "failed to read mvhd flags")
+	var creationTime uint64
+	var modificationTime uint64
+	if version == 1 {
+		err = binary.Read(mvhdIn, binary.BigEndian, &creationTime)
+		catchFile(err, in.Name(), "creation time 64")
+		err = binary.Read(mvhdIn, binary.BigEndian, &modificationTime)
+		catchFile(err, in.Name(), "modification time 64")
+	} else {
+		var creationTime32 uint32
+		var modificationTime32 uint32
+		err = binary.Read(mvhdIn, binary.BigEndian, &creationTime32)
+		catchFile(err, in.Name(), "creation time 32")
+		err = binary.Read(mvhdIn, binary.BigEndian, &modificationTime32)
+		catchFile(err, in.Name(), "modification time 32")
+		creationTime = uint64(creationTime32)
+		modificationTime = uint64(modificationTime32)
+	}
+	// zero means the time was never set:
+	if creationTime != 0 {
+		candidates = append(candidates, Candidate{SourceOriginal, "mvhd creation", quicktimeTime(creationTime), false})
+	}
+	if modificationTime != 0 {
+		candidates = append(candidates, Candidate{SourceModified, "mvhd modification", quicktimeTime(modificationTime), false})
+	}
+	return candidates
+}
+
+// quicktimeShiftTimestamps returns patches moving creation and modification times
+// of movie, track and media headers by the shift, unset times are left as they are.
+func quicktimeShiftTimestamps(in reader, shift time.Duration) []Patch {
+	moovIn, err := quicktimeSearchBox(in, "moov")
+	catchFile(err, in.Name(), "moov box not found")
+	mvhdIn, err := quicktimeSearchBox(moovIn, "mvhd")
+	catchFile(err, in.Name(), "mvhd box not found")
+	var patches = _quicktimeShiftHeader(mvhdIn, "mvhd", shift)
+	for index, trakIn := range quicktimeSearchBoxes(moovIn, "trak") {
+		if tkhdIn, err := quicktimeSearchBox(trakIn, "tkhd"); err == nil {
+			patches = append(patches, _quicktimeShiftHeader(tkhdIn, fmt.Sprintf("trak %d tkhd", index+1), shift)...)
+		}
+		if mdhdIn, err := quicktimeSearchBoxPath(trakIn, "mdia", "mdhd"); err == nil {
+			patches = append(patches, _quicktimeShiftHeader(mdhdIn, fmt.Sprintf("trak %d mdhd", index+1), shift)...)
+		}
+	}
+	return patches
+}
+
+// _quicktimeShiftHeader shifts times of mvhd, tkhd or mdhd box,
+// all start with version, flags, creation and modification times.
+func _quicktimeShiftHeader(box reader, name string, shift time.Duration) []Patch {
+	var header = make([]byte, 20)
+	_, err := box.ReadAt(header, 0)
+	catchFile(err, box.Name(), "failed to read "+name+" box")
+	var version = header[0]
+	if version > 1 {
+		raiseFmtFile(box.Name(), "unsupported %s version: %d", name, version)
+	}
+	var patches []Patch
+	for index, field := range []string{"creation", "modification"} {
+		var seconds uint64
+		var length int64 = 4
+		if version == 1 {
+			length = 8
+			seconds = binary.BigEndian.Uint64(header[4+8*index:])
+		} else {
+			seconds = uint64(binary.BigEndian.Uint32(header[4+4*index:]))
+		}
+		// zero means the time was never set:
+		if seconds == 0 {
+			continue
+		}
+		var shifted = int64(seconds) + int64(shift/time.Second)
+		if shifted <= 0 || (version == 0 && shifted > math.MaxUint32) {
+			raiseFmtFile(box.Name(), "shifted %s %s time does not fit the field", name, field)
+		}
+		var data = make([]byte, length)
+		if version == 1 {
+			binary.BigEndian.PutUint64(data, uint64(shifted))
+		} else {
+			binary.BigEndian.PutUint32(data, uint32(shifted))
+		}
+		patches = append(patches, Patch{
+			Tag:    name + " " + field,
+			Offset: box.Origin() + 4 + length*int64(index),
+			Old:    quicktimeTime(seconds),
+			New:    quicktimeTime(uint64(shifted)),
+			Data:   data,
+		})
+	}
+	return patches
+}
+
+// quicktimeTime converts seconds since 1904 to time.
+func quicktimeTime(seconds uint64) time.Time {
+	return time.Unix(int64(seconds-uint64(quicktimeEpochOffset)), 0)
+}
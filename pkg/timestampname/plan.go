@@ -0,0 +1,240 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// File is an input of the rename planning.
+type File struct {
+	Name string
+	// Time is the creation time, usually Result.Time.
+	Time   time.Time
+	Source string
+	// Camera identifies the recording device, usually Result.Camera.String().
+	Camera string
+}
+
+// Collision policies, applied when a target name is planned twice or exists.
+const (
+	CollisionAbort = "abort"
+	// CollisionSuffix appends -1, -2 and so on to the timestamp.
+	CollisionSuffix = "suffix"
+	// CollisionSubsec appends milliseconds to the timestamp, suffix is used if they are unknown or collide too.
+	CollisionSubsec = "subsec"
+	// CollisionSkip leaves the file with its name.
+	CollisionSkip = "skip"
+)
+
+// PlanOptions control target names.
+type PlanOptions struct {
+	// NoPrefix omits the counter prefix from target names.
+	NoPrefix bool
+	// Append keeps files already named after their timestamp,
+	// other files are numbered after the highest counter found.
+	Append bool
+	// OnCollision is the collision policy, CollisionAbort if empty.
+	OnCollision string
+	// Exists reports whether the name is taken on the file system,
+	// names of the planned files are considered free. Nil means no name is taken.
+	Exists func(name string) bool
+	// Camera appends camera of the file to the timestamp, files with unknown camera have none.
+	Camera bool
+}
+
+// Rename is a planned rename operation.
+type Rename struct {
+	From   string
+	To     string
+	Source string
+	// Time is the creation time of the file.
+	Time time.Time
+	// Skipped is true if the file keeps its name because of a collision.
+	Skipped bool
+}
+
+func targetFileNameFormat(numberOfFiles int, noPrefix bool) string {
+	if noPrefix {
+		return "%[2]s%[3]s"
+	}
+	if numberOfFiles < 10 {
+		return "%d-%s%s"
+	}
+	if numberOfFiles < 100 {
+		return "%02d-%s%s"
+	}
+	if numberOfFiles < 1000 {
+		return "%03d-%s%s"
+	}
+	if numberOfFiles < 10000 {
+		return "%04d-%s%s"
+	}
+	if numberOfFiles < 100000 {
+		return "%05d-%s%s"
+	}
+	raiseFmt("too many files: %d", numberOfFiles)
+	return ""
+}
+
+// Plan orders files by creation time and computes target names,
+// it fails if two files end up with the same target name.
+// Files already having their target name are planned with From equal to To.
+// Plan does not access the file system, files are not checked for existence.
+func Plan(files []File, options PlanOptions) (operations []Rename, err error) {
+	defer recoverError(&err)
+
+	var targets = make(map[string]bool)
+	var names = make(map[string]bool)
+	var sorted []File
+	var highestCounter int
+	for _, file := range files {
+		names[file.Name] = true
+		counter, named := templateCounter(file, options)
+		if !options.Append || !named {
+			sorted = append(sorted, file)
+			continue
+		}
+		if targets[file.Name] {
+			raise(file.Name, "encountered twice")
+		}
+		targets[file.Name] = true
+		operations = append(operations, Rename{file.Name, file.Name, file.Source, file.Time, false})
+		if counter > highestCounter {
+			highestCounter = counter
+		}
+	}
+	sort.Slice(operations, func(i, j int) bool {
+		return operations[i].From < operations[j].From
+	})
+
+	sort.Slice(sorted, func(i, j int) bool {
+		a := sorted[i]
+		b := sorted[j]
+		aTimestamp := a.Time.Format(TimestampLayout)
+		bTimestamp := b.Time.Format(TimestampLayout)
+		if aTimestamp == bTimestamp {
+			if a.Name == b.Name {
+				raise(a.Name, "encountered twice")
+			}
+			// workaround for Android way of dealing with same-second shots:
+			// 20180430_184327.jpg
+			// 20180430_184327(0).jpg
+			aLen := len(a.Name)
+			bLen := len(b.Name)
+			if aLen == bLen {
+				return a.Name < b.Name
+			}
+			return aLen < bLen
+		}
+		return aTimestamp < bTimestamp
+	})
+
+	var targetFormat = targetFileNameFormat(highestCounter+len(sorted), options.NoPrefix)
+	var taken = func(name string) bool {
+		return targets[name] || (options.Exists != nil && !names[name] && options.Exists(name))
+	}
+	for index, file := range sorted {
+		var counter = highestCounter + index + 1
+		var camera = cameraSuffix(file, options)
+		var format = func(timestamp string) string {
+			return fmt.Sprintf(targetFormat, counter, timestamp+camera, extension(file.Name))
+		}
+		var targetName = format(file.Time.Format(TimestampLayout))
+		var skipped bool
+		// check for target name duplicates:
+		if taken(targetName) {
+			var collision = "exists on file system"
+			if targets[targetName] {
+				collision = "duplicate rename"
+			}
+			targetName, skipped = resolveCollision(file, targetName, collision, options.OnCollision, taken, format)
+		}
+		targets[targetName] = true
+		operations = append(operations, Rename{file.Name, targetName, file.Source, file.Time, skipped})
+	}
+
+	return operations, nil
+}
+
+// resolveCollision applies the policy to the taken target name,
+// format builds target name from the timestamp.
+func resolveCollision(file File, targetName string, collision string, policy string, taken func(string) bool, format func(string) string) (string, bool) {
+	var timestamp = file.Time.Format(TimestampLayout)
+	switch policy {
+	case "", CollisionAbort:
+		raise(targetName, collision)
+	case CollisionSkip:
+		debug("target name %s taken, skipping %s", targetName, file.Name)
+		return file.Name, true
+	case CollisionSubsec:
+		if file.Time.Nanosecond() > 0 {
+			var subsecName = format(fmt.Sprintf("%s-%03d", timestamp, file.Time.Nanosecond()/int(time.Millisecond)))
+			if !taken(subsecName) {
+				return subsecName, false
+			}
+		}
+		fallthrough
+	case CollisionSuffix:
+		for suffix := 1; ; suffix++ {
+			var suffixName = format(fmt.Sprintf("%s-%d", timestamp, suffix))
+			if !taken(suffixName) {
+				return suffixName, false
+			}
+		}
+	default:
+		raiseFmt("unknown collision policy: %s", policy)
+	}
+	return "", false
+}
+
+// templateCounter reports whether the file is already named after its timestamp,
+// counter is the prefix of the name, zero without prefix.
+func templateCounter(file File, options PlanOptions) (int, bool) {
+	var suffix = file.Time.Format(TimestampLayout) + cameraSuffix(file, options) + extension(file.Name)
+	if options.NoPrefix {
+		return 0, file.Name == suffix
+	}
+	if !strings.HasSuffix(file.Name, "-"+suffix) {
+		return 0, false
+	}
+	var prefix = strings.TrimSuffix(file.Name, "-"+suffix)
+	if len(prefix) == 0 || strings.Trim(prefix, "0123456789") != "" {
+		return 0, false
+	}
+	counter, err := strconv.Atoi(prefix)
+	return counter, err == nil
+}
+
+// cameraSuffix returns camera of the file for target name, letters and digits are kept,
+// other characters are replaced with dashes.
+func cameraSuffix(file File, options PlanOptions) string {
+	if !options.Camera {
+		return ""
+	}
+	var sb strings.Builder
+	for _, r := range file.Camera {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		} else if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "-") {
+			sb.WriteByte('-')
+		}
+	}
+	var camera = strings.TrimSuffix(sb.String(), "-")
+	if len(camera) == 0 {
+		return ""
+	}
+	return "-" + camera
+}
+
+// extension returns lower cased extension of the file.
+func extension(name string) string {
+	return strings.ToLower(filepath.Ext(name))
+}
@@ -0,0 +1,110 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheIndifferent/timestampname-go/internal/golden"
+)
+
+func planFile(name string, timestamp string, source string) File {
+	parsed, err := time.Parse("2006-01-02 15:04:05.999", timestamp)
+	if err != nil {
+		panic(err)
+	}
+	return File{Name: name, Time: parsed, Source: source}
+}
+
+func withCamera(file File, camera string) File {
+	file.Camera = camera
+	return file
+}
+
+// formatPlan writes one line per rename, or the error.
+func formatPlan(operations []Rename, err error) string {
+	if err != nil {
+		return "error: " + err.Error() + "\n"
+	}
+	var sb strings.Builder
+	for _, operation := range operations {
+		fmt.Fprintf(&sb, "%s => %s (%s)", operation.From, operation.To, operation.Source)
+		if operation.Skipped {
+			sb.WriteString(" skipped")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func TestPlan(t *testing.T) {
+	// counter prefix makes names unique, collisions happen without it:
+	var collisions = []File{
+		planFile("b.jpg", "2021-05-06 07:08:09.5", SourceOriginal),
+		planFile("a.jpg", "2021-05-06 07:08:09", SourceOriginal),
+		planFile("e.jpg", "2021-05-06 07:08:09", SourceOriginal),
+		planFile("c.mp4", "2021-05-06 07:08:09", SourceModified),
+		planFile("d.jpg", "2021-05-06 07:08:10", SourceOriginal),
+	}
+	var many []File
+	for i := 0; i < 12; i++ {
+		many = append(many, planFile(fmt.Sprintf("IMG_%04d.JPG", 12-i), fmt.Sprintf("2021-05-06 07:08:%02d", i), SourceOriginal))
+	}
+	var tests = []struct {
+		name    string
+		files   []File
+		options PlanOptions
+	}{
+		{"order", []File{
+			planFile("20180430_184327(0).jpg", "2018-04-30 18:43:27", SourceOriginal),
+			planFile("video.MP4", "2018-04-30 18:00:00", SourceGps),
+			planFile("20180430_184327.jpg", "2018-04-30 18:43:27", SourceOriginal),
+			planFile("scan.dng", "2017-01-01 00:00:00", SourceXmp),
+		}, PlanOptions{}},
+		{"noprefix", []File{
+			planFile("b.jpg", "2021-05-06 07:08:09", SourceOriginal),
+			planFile("20210506-070800.jpg", "2021-05-06 07:08:00", SourceOriginal),
+		}, PlanOptions{NoPrefix: true}},
+		{"counter-width", many, PlanOptions{}},
+		{"append", []File{
+			planFile("new.jpg", "2021-05-06 07:00:00", SourceOriginal),
+			planFile("02-20210506-080000.jpg", "2021-05-06 08:00:00", SourceOriginal),
+			planFile("7-20210506-090000.jpg", "2021-05-06 09:00:00", SourceOriginal),
+			planFile("3-20210506-100000.jpg", "2021-05-06 11:00:00", SourceOriginal),
+		}, PlanOptions{Append: true}},
+		{"collision-abort", collisions, PlanOptions{NoPrefix: true}},
+		{"collision-suffix", collisions, PlanOptions{NoPrefix: true, OnCollision: CollisionSuffix}},
+		{"collision-subsec", collisions, PlanOptions{NoPrefix: true, OnCollision: CollisionSubsec}},
+		{"collision-skip", collisions, PlanOptions{NoPrefix: true, OnCollision: CollisionSkip}},
+		{"exists", []File{
+			planFile("a.jpg", "2021-05-06 07:08:09", SourceOriginal),
+			planFile("1-20210506-070809.jpg", "2021-05-06 07:08:10", SourceOriginal),
+		}, PlanOptions{OnCollision: CollisionSuffix, Exists: func(name string) bool {
+			return name == "1-20210506-070809.jpg" || name == "2-20210506-070810.jpg"
+		}}},
+		{"camera", []File{
+			withCamera(planFile("a.jpg", "2021-05-06 07:08:09", SourceOriginal), "Canon EOS R5"),
+			withCamera(planFile("b.jpg", "2021-05-06 07:08:09", SourceOriginal), "ILCE-7M3 (Sony)"),
+			planFile("c.jpg", "2021-05-06 07:08:09", SourceOriginal),
+		}, PlanOptions{Camera: true}},
+		{"camera-append", []File{
+			withCamera(planFile("1-20210506-070809-Canon-EOS-R5.jpg", "2021-05-06 07:08:09", SourceOriginal), "Canon EOS R5"),
+			withCamera(planFile("1-20210506-070809.jpg", "2021-05-06 07:08:09", SourceOriginal), "Canon EOS R5"),
+		}, PlanOptions{Camera: true, Append: true}},
+		{"duplicate-name", []File{
+			planFile("a.jpg", "2021-05-06 07:08:09", SourceOriginal),
+			planFile("a.jpg", "2021-05-06 07:08:09", SourceOriginal),
+		}, PlanOptions{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			operations, err := Plan(test.files, test.options)
+			golden.Check(t, filepath.Join("plan", test.name), formatPlan(operations, err))
+		})
+	}
+}
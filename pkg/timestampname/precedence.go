@@ -0,0 +1,154 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Timestamp sources, used in candidates and in precedence.
+const (
+	SourceOriginal  = "original"
+	SourceDigitized = "digitized"
+	SourceModified  = "modified"
+	SourceXmp       = "xmp"
+	SourceGps       = "gps"
+	SourceExifGps   = "exifgps" // UTC time of Exif GPS IFD, often recorded by phones
+	SourceFilename  = "filename"
+	SourceMtime     = "mtime"
+)
+
+// Sources lists all known timestamp sources.
+var Sources = []string{
+	SourceOriginal,
+	SourceDigitized,
+	SourceModified,
+	SourceXmp,
+	SourceGps,
+	SourceExifGps,
+	SourceFilename,
+	SourceMtime,
+}
+
+// DefaultPrecedence is used when options have none:
+// GPS time first, then the earliest of embedded dates, then XMP.
+var DefaultPrecedence = [][]string{
+	{SourceGps},
+	{SourceOriginal, SourceDigitized, SourceModified},
+	{SourceXmp},
+}
+
+// Candidate is a single timestamp found in the file.
+type Candidate struct {
+	Source string
+	// Tag names the field within the source, for example "DateTimeOriginal".
+	Tag  string
+	Time time.Time
+	// Floating is true for local times recorded without a zone, such as Exif dates,
+	// Time then carries the wall clock in UTC location.
+	Floating bool
+}
+
+// In returns time of the candidate in the zone, floating times keep their wall clock.
+func (c Candidate) In(zone *time.Location) time.Time {
+	if c.Floating {
+		return time.Date(c.Time.Year(), c.Time.Month(), c.Time.Day(),
+			c.Time.Hour(), c.Time.Minute(), c.Time.Second(), c.Time.Nanosecond(), zone)
+	}
+	return c.Time.In(zone)
+}
+
+// ParsePrecedence parses comma separated list of sources,
+// every source forms a group of its own.
+func ParsePrecedence(list string) ([][]string, error) {
+	var precedence [][]string
+	var seen = make(map[string]bool)
+	for _, source := range strings.Split(list, ",") {
+		source = strings.TrimSpace(source)
+		if !isKnownSource(source) {
+			return nil, fmt.Errorf("unknown timestamp source: '%s', expected one of: %s", source, strings.Join(Sources, ","))
+		}
+		if seen[source] {
+			return nil, fmt.Errorf("timestamp source listed twice: %s", source)
+		}
+		seen[source] = true
+		precedence = append(precedence, []string{source})
+	}
+	return precedence, nil
+}
+
+func isKnownSource(source string) bool {
+	for _, known := range Sources {
+		if source == known {
+			return true
+		}
+	}
+	return false
+}
+
+func precedenceContains(precedence [][]string, source string) bool {
+	for _, group := range precedence {
+		for _, s := range group {
+			if s == source {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// standInTags name candidates used only if no other candidate of the group is found,
+// MP4 files were named by mvhd modification time before creation time was reported.
+// Preferring the source of a stand-in alone still selects it.
+var standInTags = map[string]bool{"mvhd creation": true}
+
+// selectCandidate picks candidate from the first group of sources that has any,
+// the earliest timestamp wins within a group. Reason explains the choice.
+func selectCandidate(candidates []Candidate, precedence [][]string, zone *time.Location) (Candidate, string, bool) {
+	var skipped []string
+	for _, group := range precedence {
+		selected, found := _selectGroupCandidate(candidates, group, zone, false)
+		if found == 0 {
+			selected, found = _selectGroupCandidate(candidates, group, zone, true)
+		}
+		if found == 0 {
+			skipped = append(skipped, strings.Join(group, "+"))
+			continue
+		}
+		var reason string
+		if found == 1 {
+			reason = "only timestamp of preferred source " + strings.Join(group, "+")
+		} else {
+			reason = fmt.Sprintf("earliest of %d timestamps of preferred source %s", found, strings.Join(group, "+"))
+		}
+		if len(skipped) > 0 {
+			reason += ", no timestamps of " + strings.Join(skipped, ", ")
+		}
+		return selected, reason, true
+	}
+	return Candidate{}, "", false
+}
+
+// _selectGroupCandidate returns the earliest of either stand-in or other candidates of the group
+// and the number of those found.
+func _selectGroupCandidate(candidates []Candidate, group []string, zone *time.Location, standIn bool) (Candidate, int) {
+	var selected Candidate
+	var found int
+	for _, candidate := range candidates {
+		var inGroup bool
+		for _, source := range group {
+			inGroup = inGroup || candidate.Source == source
+		}
+		if !inGroup || standInTags[candidate.Tag] != standIn {
+			continue
+		}
+		if found == 0 || candidate.In(zone).Before(selected.In(zone)) {
+			selected = candidate
+		}
+		found++
+	}
+	return selected, found
+}
@@ -0,0 +1,189 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+)
+
+// following documents were used to implement this parser:
+// http://l.web.umkc.edu/lizhu/teaching/2016sp.video-communication/ref/mp4.pdf
+// https://mpeg.chiariglione.org/standards/mpeg-4/iso-base-media-file-format
+
+// quicktimeMatch reports whether header starts with one of the top level boxes.
+func quicktimeMatch(header []byte) bool {
+	if len(header) < 8 {
+		return false
+	}
+	switch string(header[4:8]) {
+	case "ftyp", "moov", "mdat", "free", "skip", "wide":
+		return true
+	default:
+		return false
+	}
+}
+
+// _quicktimeWalkBoxes calls visit for every box on the level of provided reader,
+// box reader passed to visit is limited to the box body.
+// Walking stops when visit returns false or the end of the reader is reached,
+// trailing bytes too short for a box header are ignored.
+func _quicktimeWalkBoxes(in reader, visit func(boxType string, box reader) bool) {
+	var err error
+	var offset int64              // offset in provided reader
+	var boxType = make([]byte, 4) // 4 bytes box type
+	_, err = in.Seek(0, 0)
+	catchFile(err, in.Name(), "failed to rewind")
+	for offset+8 <= in.Size() {
+		var boxBodyLength int64 // length of the box body
+		var boxLength uint32
+		err = binary.Read(in, binary.BigEndian, &boxLength)
+		catchFile(err, in.Name(), "failed to read box length")
+		_, err = io.ReadFull(in, boxType)
+		catchFile(err, in.Name(), "failed to read box type")
+		var boxTypeString = string(boxType)
+		debug("quicktime encountered box '%s' at offset %d", boxTypeString, offset)
+		// checking for large box:
+		if boxLength == 1 {
+			var boxLargeLength uint64
+			err = binary.Read(in, binary.BigEndian, &boxLargeLength)
+			catchFile(err, in.Name(), "failed to read box large length")
+			debug("quicktime large box length: %d", boxLargeLength)
+			// box lenght includes header, have to make adjustments:
+			// 4 bytes for box length
+			// 4 bytes for box type
+			// 8 bytes for box large length
+			if boxLargeLength < 16 || boxLargeLength > uint64(in.Size()) {
+				raiseFmtFile(in.Name(), "invalid large length of box '%s' at offset %d: %d", boxTypeString, offset, boxLargeLength)
+			}
+			boxBodyLength = int64(boxLargeLength - 16)
+			offset += 16
+		} else if boxLength == 0 {
+			// box extends to the end of the file:
+			offset += 8
+			boxBodyLength = in.Size() - offset
+		} else {
+			if boxLength < 8 {
+				raiseFmtFile(in.Name(), "invalid length of box '%s' at offset %d: %d", boxTypeString, offset, boxLength)
+			}
+			// box lenght includes header, have to make adjustments:
+			// 4 bytes for box length
+			// 4 bytes for box type
+			boxBodyLength = int64(boxLength - 8)
+			offset += 8
+		}
+		if offset+boxBodyLength > in.Size() {
+			raiseFmtFile(in.Name(), "box '%s' goes over parent length", boxTypeString)
+		}
+		if !visit(boxTypeString, newReader(in, offset, boxBodyLength)) {
+			return
+		}
+
+		offset += boxBodyLength
+		_, err = in.Seek(offset, 0)
+		catchFile(err, in.Name(), "failed to seek till next box")
+	}
+}
+
+func _quicktimeSearchBox(in reader, matchName func(string) bool, matchUuid func(string) bool) reader {
+	var found reader
+	_quicktimeWalkBoxes(in, func(boxType string, box reader) bool {
+		if !matchName(boxType) {
+			return true
+		}
+		if matchUuid == nil {
+			debug("quicktime box found, with length: %d", box.Size())
+			found = box
+			return false
+		}
+		if box.Size() < 16 {
+			raise(in.Name(), "uuid box too short")
+		}
+		var uuid = make([]byte, 16)
+		_, err := io.ReadFull(box, uuid)
+		catchFile(err, in.Name(), "failed to read box uuid")
+		if matchUuid(hex.EncodeToString(uuid)) {
+			// another 16 bytes read:
+			debug("quicktime box found, with length: %d", box.Size()-16)
+			found = newReader(box, 16, box.Size()-16)
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+func quicktimeSearchUuidBox(in reader, boxUuidNeeded string) (reader, error) {
+	debug("quicktime searching for UUID box: %s", boxUuidNeeded)
+	var box = _quicktimeSearchBox(
+		in,
+		func(name string) bool {
+			return name == "uuid"
+		},
+		func(uuid string) bool {
+			return uuid == boxUuidNeeded
+		})
+	if box == nil {
+		return nil, errors.New("failed to find a box with uuid '" + boxUuidNeeded + "'")
+	}
+	return box, nil
+}
+
+func quicktimeSearchBox(in reader, boxTypeNeeded string) (reader, error) {
+	debug("quicktime searching for box: %s", boxTypeNeeded)
+	var box = _quicktimeSearchBox(
+		in,
+		func(name string) bool {
+			return name == boxTypeNeeded
+		},
+		nil)
+	if box == nil {
+		return nil, errors.New("failed to find a box with type '" + boxTypeNeeded + "'")
+	}
+	return box, nil
+}
+
+func quicktimeSearchBoxes(in reader, boxTypeNeeded string) []reader {
+	debug("quicktime searching for all boxes: %s", boxTypeNeeded)
+	var boxes []reader
+	_quicktimeWalkBoxes(in, func(boxType string, box reader) bool {
+		if boxType == boxTypeNeeded {
+			boxes = append(boxes, box)
+		}
+		return true
+	})
+	return boxes
+}
+
+// quicktimeSearchBoxPath descends through the nested boxes,
+// for example "mdia", "minf", "stbl" for the sample table of a track.
+func quicktimeSearchBoxPath(in reader, path ...string) (reader, error) {
+	var box = in
+	for _, boxType := range path {
+		var err error
+		box, err = quicktimeSearchBox(box, boxType)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return box, nil
+}
+
+// quicktimeSearchXmp returns XMP packet from the top level uuid box,
+// or from moov/udta/XMP_ box used by QuickTime, or nil if there is none.
+func quicktimeSearchXmp(in reader) []byte {
+	var box, err = quicktimeSearchUuidBox(in, "be7acfcb97a942e89c71999491e3afac")
+	if err != nil {
+		box, err = quicktimeSearchBoxPath(in, "moov", "udta", "XMP_")
+		if err != nil {
+			return nil
+		}
+	}
+	var packet = make([]byte, box.Size())
+	_, err = io.ReadFull(box, packet)
+	catchFile(err, in.Name(), "failed to read XMP box")
+	return packet
+}
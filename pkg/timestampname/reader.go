@@ -15,25 +15,32 @@ type reader interface {
 	Seek(offset int64, whence int) (int64, error)
 	Size() int64
 	Name() string
+	// Origin is the offset of the reader start in the file.
+	Origin() int64
 }
 
 type fileSectionReader struct {
 	*io.SectionReader
-	name string
+	name   string
+	origin int64
 }
 
 func (in *fileSectionReader) Name() string {
 	return in.name
 }
 
+func (in *fileSectionReader) Origin() int64 {
+	return in.origin
+}
+
 func newFileReader(file *os.File, name string) reader {
 	stat, err := file.Stat()
-	CatchFile(err, name, "failed to stat")
-	return &fileSectionReader{io.NewSectionReader(file, 0, stat.Size()), name}
+	catchFile(err, name, "failed to stat")
+	return &fileSectionReader{io.NewSectionReader(file, 0, stat.Size()), name, 0}
 }
 
 func newReader(r reader, off int64, n int64) reader {
-	if cmdArgs.debugOutput {
+	if debugLogger != nil {
 		var reflectedReader = reflect.ValueOf(r).Elem()
 		debug("creating reader: from: {base:%d, off:%d, limit:%d}, new offset: %d, new size: %d",
 			reflectedReader.FieldByName("base"),
@@ -42,5 +49,9 @@ func newReader(r reader, off int64, n int64) reader {
 			off,
 			n)
 	}
-	return &fileSectionReader{io.NewSectionReader(r, off, n), r.Name()}
+	return &fileSectionReader{io.NewSectionReader(r, off, n), r.Name(), r.Origin() + off}
+}
+
+func newReaderAt(r io.ReaderAt, size int64, name string) reader {
+	return &fileSectionReader{io.NewSectionReader(r, 0, size), name, 0}
 }
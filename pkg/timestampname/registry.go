@@ -0,0 +1,125 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HeaderLength is the number of leading bytes passed to Extractor.Match,
+// header is shorter if the content is.
+const HeaderLength = 32
+
+// Extractor extracts timestamps from files of a single format.
+// Built-in formats are registered on package initialization,
+// additional ones can be added with Register.
+type Extractor interface {
+	// Name of the format, for diagnostics.
+	Name() string
+	// Extensions handled by the extractor, lower case with leading dot.
+	Extensions() []string
+	// Match reports whether the header belongs to the format.
+	Match(header []byte) bool
+	// Priority decides between extractors matching the same content, higher wins.
+	Priority() int
+	// Extract returns all timestamps found in the content,
+	// name is the file name or the hint given by the caller.
+	Extract(r io.ReaderAt, size int64, name string) ([]Candidate, error)
+}
+
+var (
+	registryLock sync.RWMutex
+	registry     []Extractor
+)
+
+// Register adds extractor to the registry, it is safe to call concurrently.
+func Register(e Extractor) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	registry = append(registry, e)
+	// stable sort keeps registration order for the same priority:
+	sort.SliceStable(registry, func(i, j int) bool {
+		return registry[i].Priority() > registry[j].Priority()
+	})
+}
+
+// Supported reports whether any registered extractor handles extension of the file.
+func Supported(name string) bool {
+	var ext = extension(name)
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	for _, e := range registry {
+		if handlesExtension(e, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupExtractor chooses the highest priority extractor handling the extension and matching the header,
+// if none handles the extension the content may be misnamed, any matching the header is chosen.
+func lookupExtractor(header []byte, ext string) (Extractor, bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	for _, e := range registry {
+		if handlesExtension(e, ext) && e.Match(header) {
+			return e, true
+		}
+	}
+	for _, e := range registry {
+		if e.Match(header) {
+			debug("extension '%s' does not match content, using %s extractor", ext, e.Name())
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+func handlesExtension(e Extractor, ext string) bool {
+	for _, handled := range e.Extensions() {
+		if handled == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// builtinExtractor adapts parsers of this package to Extractor.
+type builtinExtractor struct {
+	name       string
+	extensions []string
+	match      func(header []byte) bool
+	priority   int
+	extract    func(in reader) []Candidate
+	// searchXmp returns embedded XMP packet, may be nil if format has none:
+	searchXmp func(in reader) []byte
+	// shift returns patches moving embedded timestamps, may be nil if writing is not supported:
+	shift func(in reader, shift time.Duration) []Patch
+	// camera returns identity of the recording device, may be nil if format has none:
+	camera func(in reader) Camera
+}
+
+func (e *builtinExtractor) Name() string {
+	return e.name
+}
+
+func (e *builtinExtractor) Extensions() []string {
+	return e.extensions
+}
+
+func (e *builtinExtractor) Match(header []byte) bool {
+	return e.match(header)
+}
+
+func (e *builtinExtractor) Priority() int {
+	return e.priority
+}
+
+func (e *builtinExtractor) Extract(r io.ReaderAt, size int64, name string) (candidates []Candidate, err error) {
+	defer recoverError(&err)
+	return e.extract(newReaderAt(r, size, name)), nil
+}
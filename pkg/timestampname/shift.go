@@ -0,0 +1,53 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"io"
+	"time"
+)
+
+// Patch is an in-place change of an embedded timestamp.
+type Patch struct {
+	Tag string
+	// Offset of the value from the start of the content.
+	Offset int64
+	Old    time.Time
+	New    time.Time
+	// Data is the encoded new value, same length as the old one.
+	Data []byte
+}
+
+// ShiftTimestamps locates embedded timestamps of the content and returns patches moving them by the shift,
+// hint is the file name or extension used to detect the format.
+// Exif dates in TIFF, JPEG, DNG and NEF, QuickTime mvhd, tkhd and mdhd times in MP4 and CR3 are supported,
+// GPS times are left as they are. Shift must be whole seconds.
+func ShiftTimestamps(r io.ReaderAt, size int64, hint string, shift time.Duration) (patches []Patch, err error) {
+	defer recoverError(&err)
+	if shift%time.Second != 0 {
+		raiseFmtFile(hint, "shift must be whole seconds: %v", shift)
+	}
+	var in = newReaderAt(r, size, hint)
+	var header = make([]byte, HeaderLength)
+	n, _ := in.ReadAt(header, 0)
+	extractor, found := lookupExtractor(header[:n], extension(hint))
+	if !found {
+		raise(hint, "content does not match any known format")
+	}
+	builtin, isBuiltin := extractor.(*builtinExtractor)
+	if !isBuiltin || builtin.shift == nil {
+		raiseFmtFile(hint, "writing timestamps is not supported for %s", extractor.Name())
+	}
+	return builtin.shift(in, shift), nil
+}
+
+// WritePatches writes the patches to the content.
+func WritePatches(w io.WriterAt, patches []Patch) error {
+	for _, patch := range patches {
+		if _, err := w.WriteAt(patch.Data, patch.Offset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
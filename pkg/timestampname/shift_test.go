@@ -0,0 +1,61 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// patchedContent is the content patches are written to.
+type patchedContent []byte
+
+func (c patchedContent) WriteAt(p []byte, off int64) (int, error) {
+	return copy(c[off:], p), nil
+}
+
+func TestShiftTimestamps(t *testing.T) {
+	var shift = -90 * time.Minute
+	// WAV and MP3 are not supported:
+	var supported = map[string]bool{".jpg": true, ".dng": true, ".cr3": true, ".mp4": true}
+	for _, test := range extractTests() {
+		if !supported[test.hint] || (test.source != SourceOriginal && test.source != SourceModified) {
+			continue
+		}
+		t.Run(test.name, func(t *testing.T) {
+			var data = append(patchedContent{}, test.data...)
+			patches, err := ShiftTimestamps(bytes.NewReader(data), int64(len(data)), test.hint, shift)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(patches) == 0 {
+				t.Fatal("no patches")
+			}
+			for _, patch := range patches {
+				if !patch.New.Equal(patch.Old.Add(shift)) {
+					t.Errorf("patch %s moves %v to %v", patch.Tag, patch.Old, patch.New)
+				}
+			}
+			if err = WritePatches(data, patches); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			result, err := ExtractCreationTime(bytes.NewReader(data), int64(len(data)), test.hint, test.options)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// sub-seconds are not shifted, they are added to the shifted date:
+			if !result.Time.Equal(test.time.Add(shift)) || result.Tag != test.tag {
+				t.Errorf("got %v %s, want %v %s", result.Time, result.Tag, test.time.Add(shift), test.tag)
+			}
+		})
+	}
+}
+
+func TestShiftTimestampsWholeSeconds(t *testing.T) {
+	var data = jpegFixture(tiffFixture(fixtureByteOrders[0], testExif), nil)
+	if _, err := ShiftTimestamps(bytes.NewReader(data), int64(len(data)), ".jpg", time.Millisecond); err == nil {
+		t.Error("expected error for shift of a fraction of second")
+	}
+}
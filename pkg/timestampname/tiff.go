@@ -0,0 +1,254 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	tiffEndianessLittle = binary.BigEndian.Uint16([]byte("II"))
+	tiffEndianessBig    = binary.BigEndian.Uint16([]byte("MM"))
+)
+
+func init() {
+	Register(&builtinExtractor{
+		name:       "TIFF",
+		extensions: []string{".dng", ".nef"},
+		match: func(header []byte) bool {
+			return bytes.HasPrefix(header, []byte("II*\x00")) || bytes.HasPrefix(header, []byte("MM\x00*"))
+		},
+		extract:   tiffExtractTimestampCandidates,
+		searchXmp: tiffSearchXmp,
+		shift:     tiffShiftTimestamps,
+		camera:    tiffCamera,
+	})
+}
+
+// _tiffReadHeader reads byte order and the offset of the first IFD.
+func _tiffReadHeader(in reader) (binary.ByteOrder, uint32) {
+	// Bytes 0-1: The byte order used within the file. Legal values are:
+	// “II” (4949.H)
+	// “MM” (4D4D.H)
+	var tiffEndianess uint16
+	// smart thing about specification, we can supplly any endianess:
+	err := binary.Read(in, binary.LittleEndian, &tiffEndianess)
+	catchFile(err, in.Name(), "failed to read file header")
+
+	// In the “II” format, byte order is always from the least significant byte to the most
+	// significant byte, for both 16-bit and 32-bit integers.
+	// This is called little-endian byte order.
+	//  In the “MM” format, byte order is always from most significant to least
+	// significant, for both 16-bit and 32-bit integers.
+	// This is called big-endian byte order
+	var bo binary.ByteOrder
+	switch tiffEndianess {
+	case tiffEndianessBig:
+		bo = binary.BigEndian
+	case tiffEndianessLittle:
+		bo = binary.LittleEndian
+	default:
+		raiseFmtFile(in.Name(), "invalid TIFF file header: %d", tiffEndianess)
+	}
+	debug("TIFF endianess: %v", bo)
+
+	// Bytes 2-3 An arbitrary but carefully chosen number (42)
+	// that further identifies the file as a TIFF file.
+	var tiffMagic uint16
+	err = binary.Read(in, bo, &tiffMagic)
+	catchFile(err, in.Name(), "failed to read TIFF magic number")
+	if tiffMagic != 42 {
+		raiseFmtFile(in.Name(), "invalid TIFF magic number: %d", tiffMagic)
+	}
+
+	// Bytes 4-7 The offset (in bytes) of the first IFD.
+	var firstIfdOffset uint32
+	err = binary.Read(in, bo, &firstIfdOffset)
+	catchFile(err, in.Name(), "failed to read IFD offset")
+	return bo, firstIfdOffset
+}
+
+// limits of number of IFDs and of their entries in a file, IFDs may overlap:
+const (
+	tiffMaxIfds    = 256
+	tiffMaxEntries = 1 << 16
+)
+
+// tiffDateTags maps date tags to timestamp sources.
+var tiffDateTags = map[uint16]struct {
+	name   string
+	source string
+}{
+	0x0132: {"DateTime", SourceModified},
+	0x9003: {"DateTimeOriginal", SourceOriginal},
+	0x9004: {"DateTimeDigitized", SourceDigitized},
+}
+
+// tiffSubSecTags maps sub-second tags to date tags they complement.
+var tiffSubSecTags = map[uint16]uint16{
+	0x9290: 0x0132, // SubSecTime
+	0x9291: 0x9003, // SubSecTimeOriginal
+	0x9292: 0x9004, // SubSecTimeDigitized
+}
+
+// _tiffParseSubSec parses fraction digits of sub-second tag, returns false if the value has none.
+func _tiffParseSubSec(value []byte) (time.Duration, bool) {
+	var digits = strings.TrimRight(string(value), "\x00 ")
+	if len(digits) == 0 || strings.Trim(digits, "0123456789") != "" {
+		return 0, false
+	}
+	// fraction of a second, padded or truncated to nanoseconds:
+	digits = (digits + "000000000")[:9]
+	nanoseconds, err := strconv.Atoi(digits)
+	return time.Duration(nanoseconds), err == nil
+}
+
+// _tiffParseDate parses Exif date, returns false if the value is not a valid date.
+func _tiffParseDate(value string) (time.Time, bool) {
+	parsed, parseError := time.Parse("2006:01:02 15:04:05", value)
+	if parseError != nil {
+		// bug in Samsung S9 camera, panorama photo has different date format:
+		parsed2, parseError2 := time.Parse("2006-01-02 15:04:05", value)
+		if parseError2 != nil {
+			debug("TIFF failed to parse exif date: %s, %v, %v", value, parseError, parseError2)
+			return time.Time{}, false
+		}
+		parsed = parsed2
+	}
+	return parsed, true
+}
+
+// tiffDate is a date tag value found in the file.
+type tiffDate struct {
+	Candidate
+	// offset of the value from the start of the file:
+	offset int64
+	value  string
+}
+
+func tiffExtractTimestampCandidates(in reader) []Candidate {
+	var candidates []Candidate
+	for _, date := range _tiffCollectDates(in) {
+		candidates = append(candidates, date.Candidate)
+	}
+	return append(candidates, tiffGpsCandidates(in)...)
+}
+
+// tiffGpsCandidates returns UTC time of GPSDateStamp (0x001D) and GPSTimeStamp (0x0007) tags of GPS IFD,
+// invalid or incomplete values are ignored.
+func tiffGpsCandidates(in reader) []Candidate {
+	for _, ifd := range tiffWalkIfds(in) {
+		if ifd.name != tiffGpsIfd {
+			continue
+		}
+		dateEntry, dateFound := ifd.entry(0x001D)
+		timeEntry, timeFound := ifd.entry(0x0007)
+		if !dateFound || !timeFound || dateEntry.typ != 2 || timeEntry.typ != 5 || timeEntry.count != 3 {
+			debug("TIFF GPS IFD has no date and time stamp")
+			return nil
+		}
+		if gpsTime, valid := _tiffParseGpsTime(dateEntry.ascii(), timeEntry.rationals()); valid {
+			return []Candidate{{SourceExifGps, "GPSDateStamp GPSTimeStamp", gpsTime, false}}
+		}
+	}
+	return nil
+}
+
+// _tiffParseGpsTime parses GPS date and hours, minutes and seconds of the day in UTC,
+// returns false if the values are not valid.
+func _tiffParseGpsTime(date string, hms []float64) (time.Time, bool) {
+	day, err := time.Parse("2006:01:02", date)
+	if err != nil {
+		debug("TIFF failed to parse GPS date: %s, %v", date, err)
+		return time.Time{}, false
+	}
+	// comparisons are false for NaN of zero denominators:
+	if !(hms[0] >= 0 && hms[0] < 24 && hms[1] >= 0 && hms[1] < 60 && hms[2] >= 0 && hms[2] < 61) {
+		debug("TIFF invalid GPS time: %v", hms)
+		return time.Time{}, false
+	}
+	var seconds = hms[0]*60*60 + hms[1]*60 + hms[2]
+	return day.Add(time.Duration(seconds * float64(time.Second))), true
+}
+
+// tiffShiftTimestamps returns patches moving date tag values by the shift,
+// values keep their layout, sub-second tags are not changed.
+func tiffShiftTimestamps(in reader, shift time.Duration) []Patch {
+	var patches []Patch
+	for _, date := range _tiffCollectDates(in) {
+		var layout = "2006:01:02 15:04:05"
+		if date.value[4] == '-' {
+			layout = "2006-01-02 15:04:05"
+		}
+		var shifted = date.Time.Add(shift)
+		patches = append(patches, Patch{date.Tag, date.offset, date.Time, shifted, []byte(shifted.Format(layout))})
+	}
+	return patches
+}
+
+// _tiffCollectDates collects date tags of every IFD with their sub-seconds.
+func _tiffCollectDates(in reader) []tiffDate {
+	debug("TIFF processing file: %s", in.Name())
+	var subSecondsByTag = make(map[string]time.Duration)
+	var dates []tiffDate
+	for _, ifd := range tiffWalkIfds(in) {
+		// GPS tags numbers overlap with other tags:
+		if ifd.name == tiffGpsIfd {
+			continue
+		}
+		for _, entry := range ifd.entries {
+			// 0x0132: DateTime
+			// 0x9003: DateTimeOriginal
+			// 0x9004: DateTimeDigitized
+			if dateTag, isDateTag := tiffDateTags[entry.tag]; isDateTag {
+				if entry.typ != 2 {
+					raiseFmtFile(in.Name(), "expected tag has unexpected type: %d == %d", entry.tag, entry.typ)
+				}
+				if entry.count != 20 {
+					raiseFmtFile(in.Name(), "expected tag has unexpected size: %d == %d", entry.tag, entry.count)
+				}
+				debug("TIFF collecting date at offset: %d", entry.offset)
+				var dateValue = string(entry.bytes()[:19])
+				debug("TIFF date value read: %s", dateValue)
+				if parsed, valid := _tiffParseDate(dateValue); valid {
+					dates = append(dates, tiffDate{Candidate{dateTag.source, dateTag.name, parsed, true}, in.Origin() + entry.offset, dateValue})
+				}
+			}
+			// 0x9290-0x9292: SubSecTime, SubSecTimeOriginal, SubSecTimeDigitized
+			if dateTag, isSubSecTag := tiffSubSecTags[entry.tag]; isSubSecTag && entry.typ == 2 && entry.count <= 16 {
+				if subSeconds, valid := _tiffParseSubSec(entry.bytes()); valid {
+					debug("TIFF sub-seconds for tag: %d => %v", dateTag, subSeconds)
+					subSecondsByTag[tiffDateTags[dateTag].name] = subSeconds
+				}
+			}
+		}
+	}
+
+	for index := range dates {
+		dates[index].Time = dates[index].Time.Add(subSecondsByTag[dates[index].Tag])
+	}
+
+	// fast-forward to the end:
+	in.Seek(0, 2)
+	return dates
+}
+
+// tiffSearchXmp returns XMP packet referenced by tag 700 of the first IFD, or nil if there is none.
+func tiffSearchXmp(in reader) []byte {
+	_, err := in.Seek(0, 0)
+	catchFile(err, in.Name(), "failed to rewind")
+	var bo, ifdOffset = _tiffReadHeader(in)
+	ifd, _ := _tiffReadIfd(in, bo, "IFD0", int64(ifdOffset))
+	// 0x02BC: XMP, BYTE or UNDEFINED type:
+	entry, found := ifd.entry(0x02BC)
+	if !found || entry.count <= 4 || (entry.typ != 1 && entry.typ != 7) {
+		return nil
+	}
+	debug("TIFF XMP packet found at offset: %d", entry.offset)
+	return entry.bytes()
+}
@@ -0,0 +1,241 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// names of IFDs reported by the walker, IFD chain is named IFD0, IFD1 and so on:
+const (
+	tiffExifIfd    = "Exif"
+	tiffGpsIfd     = "GPS"
+	tiffSubIfd     = "SubIFD"
+	tiffInteropIfd = "Interop"
+)
+
+// tiffIfdPointers maps tags pointing to other IFDs to names of those IFDs.
+// MakerNote (0x927C) is not followed, its layout is vendor specific
+// and offsets in it are often relative to the note instead of the TIFF header.
+var tiffIfdPointers = map[uint16]string{
+	0x014A: tiffSubIfd,     // SubIFDs, DNG and NEF raw images
+	0x8769: tiffExifIfd,    // ExifIFDPointer
+	0x8825: tiffGpsIfd,     // GPSInfoIFDPointer
+	0xA005: tiffInteropIfd, // InteroperabilityIFDPointer
+}
+
+// tiffTypeSizes maps field types to size of a single value in bytes.
+var tiffTypeSizes = map[uint16]int64{
+	1:  1, // BYTE
+	2:  1, // ASCII
+	3:  2, // SHORT
+	4:  4, // LONG
+	5:  8, // RATIONAL
+	6:  1, // SBYTE
+	7:  1, // UNDEFINED
+	8:  2, // SSHORT
+	9:  4, // SLONG
+	10: 8, // SRATIONAL
+	11: 4, // FLOAT
+	12: 8, // DOUBLE
+	13: 4, // IFD
+}
+
+// tiffIfd is an image file directory.
+type tiffIfd struct {
+	name    string
+	offset  int64
+	entries []tiffEntry
+}
+
+// entry returns the entry with the tag, false if the IFD has none.
+func (ifd tiffIfd) entry(tag uint16) (tiffEntry, bool) {
+	for _, entry := range ifd.entries {
+		if entry.tag == tag {
+			return entry, true
+		}
+	}
+	return tiffEntry{}, false
+}
+
+// tiffEntry is a field of IFD, the value is read on demand.
+type tiffEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	// offset of the value from the start of the reader, values up to 4 bytes are stored in the entry itself:
+	offset int64
+	in     reader
+	bo     binary.ByteOrder
+}
+
+// size returns size of the value in bytes, zero for unknown types.
+func (e tiffEntry) size() int64 {
+	return tiffTypeSizes[e.typ] * int64(e.count)
+}
+
+// bytes reads the raw value.
+func (e tiffEntry) bytes() []byte {
+	if e.offset+e.size() > e.in.Size() {
+		raiseFmtFile(e.in.Name(), "value of TIFF tag %d goes over file length", e.tag)
+	}
+	var value = make([]byte, e.size())
+	_, err := e.in.ReadAt(value, e.offset)
+	catchFile(err, e.in.Name(), "failed to read TIFF tag value")
+	return value
+}
+
+// ascii reads ASCII value without trailing NULs.
+func (e tiffEntry) ascii() string {
+	if e.typ != 2 {
+		raiseFmtFile(e.in.Name(), "TIFF tag %d is not ASCII: %d", e.tag, e.typ)
+	}
+	return strings.TrimRight(string(e.bytes()), "\x00")
+}
+
+// uints reads values of unsigned integer types.
+func (e tiffEntry) uints() []uint64 {
+	if e.typ != 1 && e.typ != 3 && e.typ != 4 && e.typ != 13 {
+		raiseFmtFile(e.in.Name(), "TIFF tag %d is not unsigned integer: %d", e.tag, e.typ)
+	}
+	var value = e.bytes()
+	var values = make([]uint64, e.count)
+	for i := range values {
+		switch e.typ {
+		case 1:
+			values[i] = uint64(value[i])
+		case 3:
+			values[i] = uint64(e.bo.Uint16(value[2*i:]))
+		default:
+			values[i] = uint64(e.bo.Uint32(value[4*i:]))
+		}
+	}
+	return values
+}
+
+// rationals reads values of rational types, zero denominators give infinity or NaN.
+func (e tiffEntry) rationals() []float64 {
+	if e.typ != 5 && e.typ != 10 && e.typ != 12 {
+		raiseFmtFile(e.in.Name(), "TIFF tag %d is not rational: %d", e.tag, e.typ)
+	}
+	var value = e.bytes()
+	var values = make([]float64, e.count)
+	for i := range values {
+		switch e.typ {
+		case 5:
+			values[i] = float64(e.bo.Uint32(value[8*i:])) / float64(e.bo.Uint32(value[8*i+4:]))
+		case 10:
+			values[i] = float64(int32(e.bo.Uint32(value[8*i:]))) / float64(int32(e.bo.Uint32(value[8*i+4:])))
+		default:
+			values[i] = math.Float64frombits(e.bo.Uint64(value[8*i:]))
+		}
+	}
+	return values
+}
+
+// tiffWalkIfds reads every IFD of the TIFF structure: the IFD chain
+// and IFDs referenced by pointer tags, including SubIFDs and GPS IFD.
+// https://www.adobe.io/content/dam/udp/en/open/standards/tiff/TIFF6.pdf
+func tiffWalkIfds(in reader) []tiffIfd {
+	_, err := in.Seek(0, 0)
+	catchFile(err, in.Name(), "failed to rewind")
+	var bo, firstIfdOffset = _tiffReadHeader(in)
+	type pendingIfd struct {
+		name   string
+		offset int64
+		// position in the chain, IFD0 is 0:
+		index int
+	}
+	var pending = []pendingIfd{{"IFD0", int64(firstIfdOffset), 0}}
+	var visitedIfds = make(map[int64]bool)
+	var ifds []tiffIfd
+	var entries int
+	for len(pending) > 0 {
+		var next = pending[0]
+		pending = pending[1:]
+		// IFD chain pointing back makes a loop, IFDs shared by several pointers are read once:
+		if visitedIfds[next.offset] {
+			debug("TIFF skipping %s at offset %d, already visited", next.name, next.offset)
+			continue
+		}
+		visitedIfds[next.offset] = true
+		if len(visitedIfds) > tiffMaxIfds {
+			raiseFmtFile(in.Name(), "more than %d IFDs", tiffMaxIfds)
+		}
+		debug("TIFF walking %s at offset: %d", next.name, next.offset)
+		ifd, nextIfdOffset := _tiffReadIfd(in, bo, next.name, next.offset)
+		entries += len(ifd.entries)
+		if entries > tiffMaxEntries {
+			raiseFmtFile(in.Name(), "more than %d IFD entries", tiffMaxEntries)
+		}
+		ifds = append(ifds, ifd)
+		for _, entry := range ifd.entries {
+			name, isPointer := tiffIfdPointers[entry.tag]
+			if !isPointer {
+				continue
+			}
+			if entry.typ != 4 && entry.typ != 13 {
+				raiseFmtFile(in.Name(), "IFD pointer tag has unexpected type: %d == %d", entry.tag, entry.typ)
+			}
+			for _, offset := range entry.uints() {
+				debug("TIFF %s offset: %d", name, offset)
+				pending = append(pending, pendingIfd{name, int64(offset), 0})
+			}
+		}
+		if nextIfdOffset != 0 {
+			var name = next.name
+			if strings.HasPrefix(name, "IFD") {
+				name = fmt.Sprintf("IFD%d", next.index+1)
+			}
+			pending = append(pending, pendingIfd{name, nextIfdOffset, next.index + 1})
+		}
+		if len(visitedIfds)+len(pending) > tiffMaxIfds {
+			raiseFmtFile(in.Name(), "more than %d IFDs", tiffMaxIfds)
+		}
+	}
+	return ifds
+}
+
+// _tiffReadIfd reads entries of the IFD at the offset, returns the IFD and offset of the next one, zero if none.
+func _tiffReadIfd(in reader, bo binary.ByteOrder, name string, offset int64) (tiffIfd, int64) {
+	// 2-byte count of the number of directory entries (i.e., the number of fields)
+	if offset+2 > in.Size() {
+		raise(in.Name(), "IFD offset goes over file length")
+	}
+	var buffer = make([]byte, 2)
+	_, err := in.ReadAt(buffer, offset)
+	catchFile(err, in.Name(), "failed to read number of IFD entries")
+	var fields = int64(bo.Uint16(buffer))
+	debug("TIFF fields: %d", fields)
+	// 12 bytes per field, followed by a 4-byte offset of the next IFD (or 0 if none):
+	if offset+2+12*fields+4 > in.Size() {
+		raise(in.Name(), "IFD goes over file length")
+	}
+	buffer = make([]byte, 12*fields+4)
+	_, err = in.ReadAt(buffer, offset+2)
+	catchFile(err, in.Name(), "failed to read IFD entries")
+
+	var ifd = tiffIfd{name: name, offset: offset}
+	for t := int64(0); t < fields; t++ {
+		// Bytes 0-1 tag, 2-3 type, 4-7 count, 8-11 value or offset of the value:
+		var field = buffer[12*t : 12*t+12]
+		var entry = tiffEntry{
+			tag:    bo.Uint16(field),
+			typ:    bo.Uint16(field[2:]),
+			count:  bo.Uint32(field[4:]),
+			offset: offset + 2 + 12*t + 8,
+			in:     in,
+			bo:     bo,
+		}
+		if entry.size() > 4 {
+			entry.offset = int64(bo.Uint32(field[8:]))
+		}
+		debug("TIFF field: tag=%d, type=%d, count=%d, offset=%d", entry.tag, entry.typ, entry.count, entry.offset)
+		ifd.entries = append(ifd.entries, entry)
+	}
+	return ifd, int64(bo.Uint32(buffer[12*fields:]))
+}
@@ -0,0 +1,75 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func walkIfdNames(t *testing.T, data []byte) string {
+	var names []string
+	err := try(func() {
+		for _, ifd := range tiffWalkIfds(newReaderAt(bytes.NewReader(data), int64(len(data)), "test.dng")) {
+			names = append(names, ifd.name)
+		}
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return strings.Join(names, " ")
+}
+
+func TestTiffWalkIfds(t *testing.T) {
+	for _, bo := range fixtureByteOrders {
+		var exif = testExif
+		exif.gpsDate = "2021:05:06"
+		if names := walkIfdNames(t, tiffFixture(bo, exif)); names != "IFD0 Exif GPS IFD1" {
+			t.Errorf("%s: got IFDs %s", byteOrderName(bo), names)
+		}
+		// SubIFD referenced twice is read once:
+		if names := walkIfdNames(t, dngFixture(bo, exif)); names != "IFD0 SubIFD Exif GPS" {
+			t.Errorf("%s: got DNG IFDs %s", byteOrderName(bo), names)
+		}
+		// IFD chain pointing back to itself ends:
+		var w = newTiffWriter(bo)
+		if names := walkIfdNames(t, w.bytes(w.ifd(8, asciiField(0x0132, testExif.dateTime)))); names != "IFD0" {
+			t.Errorf("%s: got looped IFDs %s", byteOrderName(bo), names)
+		}
+	}
+}
+
+func TestTiffEntryValues(t *testing.T) {
+	for _, bo := range fixtureByteOrders {
+		var w = newTiffWriter(bo)
+		var data = w.bytes(w.ifd(0,
+			asciiField(0x010F, "Canon"),
+			longField(0x0111, 1, 70000),
+			rationalField(0x011A, 72.5)))
+		var ifds []tiffIfd
+		err := try(func() {
+			ifds = tiffWalkIfds(newReaderAt(bytes.NewReader(data), int64(len(data)), "test.dng"))
+		})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", byteOrderName(bo), err)
+		}
+		var ifd = ifds[0]
+		asciiEntry, _ := ifd.entry(0x010F)
+		longEntry, _ := ifd.entry(0x0111)
+		rationalEntry, _ := ifd.entry(0x011A)
+		if value := asciiEntry.ascii(); value != "Canon" {
+			t.Errorf("%s: got ASCII %q", byteOrderName(bo), value)
+		}
+		if values := longEntry.uints(); len(values) != 2 || values[0] != 1 || values[1] != 70000 {
+			t.Errorf("%s: got LONG %v", byteOrderName(bo), values)
+		}
+		if values := rationalEntry.rationals(); len(values) != 1 || values[0] != 72.5 {
+			t.Errorf("%s: got RATIONAL %v", byteOrderName(bo), values)
+		}
+		if err := try(func() { asciiEntry.uints() }); err == nil {
+			t.Errorf("%s: expected error reading ASCII as integers", byteOrderName(bo))
+		}
+	}
+}
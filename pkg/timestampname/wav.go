@@ -0,0 +1,122 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"encoding/binary"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// following resources were used to implement this parser:
+// https://tech.ebu.ch/docs/tech/tech3285.pdf
+// http://www.gallery.co.uk/ixml/
+
+var (
+	riffHeaderExpected = binary.BigEndian.Uint32([]byte("RIFF"))
+	waveHeaderExpected = binary.BigEndian.Uint32([]byte("WAVE"))
+
+	ixmlOriginationDate = regexp.MustCompile(`<BWF_ORIGINATION_DATE>\s*([^<]*?)\s*</BWF_ORIGINATION_DATE>`)
+	ixmlOriginationTime = regexp.MustCompile(`<BWF_ORIGINATION_TIME>\s*([^<]*?)\s*</BWF_ORIGINATION_TIME>`)
+)
+
+const (
+	// bext chunk: 256 bytes description, 32 bytes originator, 32 bytes originator reference,
+	// followed by 10 bytes origination date and 8 bytes origination time:
+	bextOriginationOffset = 256 + 32 + 32
+	bextOriginationLength = 10 + 8
+)
+
+func init() {
+	Register(&builtinExtractor{
+		name:       "WAV",
+		extensions: []string{".wav"},
+		match: func(header []byte) bool {
+			return len(header) >= 12 && string(header[0:4]) == "RIFF" && string(header[8:12]) == "WAVE"
+		},
+		extract: wavExtractTimestampCandidates,
+	})
+}
+
+func wavExtractTimestampCandidates(in reader) []Candidate {
+	// checking RIFF and WAVE headers:
+	var riffHeader uint32
+	var riffSize uint32
+	var waveHeader uint32
+	err := binary.Read(in, binary.BigEndian, &riffHeader)
+	catchFile(err, in.Name(), "failed to read RIFF header")
+	err = binary.Read(in, binary.LittleEndian, &riffSize)
+	catchFile(err, in.Name(), "failed to read RIFF size")
+	err = binary.Read(in, binary.BigEndian, &waveHeader)
+	catchFile(err, in.Name(), "failed to read WAVE header")
+	if riffHeader != riffHeaderExpected || waveHeader != waveHeaderExpected {
+		raise(in.Name(), "unexpected header")
+	}
+
+	var candidates []Candidate
+	var offset int64 = 12 // 4 bytes RIFF, 4 bytes size, 4 bytes WAVE
+	var chunkId = make([]byte, 4)
+	for offset+8 <= in.Size() {
+		_, err = in.Seek(offset, 0)
+		catchFile(err, in.Name(), "failed to seek till next chunk")
+		_, err = io.ReadFull(in, chunkId)
+		catchFile(err, in.Name(), "failed to read chunk id")
+		var chunkSize uint32
+		err = binary.Read(in, binary.LittleEndian, &chunkSize)
+		catchFile(err, in.Name(), "failed to read chunk size")
+		debug("WAV encountered chunk '%s' at offset %d, with length %d", chunkId, offset, chunkSize)
+		if offset+8+int64(chunkSize) > in.Size() {
+			raise(in.Name(), "chunk goes over file length")
+		}
+
+		switch string(chunkId) {
+		case "bext":
+			if chunkSize >= bextOriginationOffset+bextOriginationLength {
+				var origination = make([]byte, bextOriginationLength)
+				_, err = in.ReadAt(origination, offset+8+bextOriginationOffset)
+				catchFile(err, in.Name(), "failed to read bext origination")
+				if parsed, valid := wavParseOrigination(string(origination[:10]), string(origination[10:])); valid {
+					debug("WAV bext origination: %v", parsed)
+					candidates = append(candidates, Candidate{SourceOriginal, "bext OriginationDate", parsed, true})
+				}
+			}
+		case "iXML":
+			var body = make([]byte, chunkSize)
+			_, err = io.ReadFull(in, body)
+			catchFile(err, in.Name(), "failed to read iXML chunk")
+			var dateMatch = ixmlOriginationDate.FindSubmatch(body)
+			var timeMatch = ixmlOriginationTime.FindSubmatch(body)
+			if dateMatch != nil && timeMatch != nil {
+				if parsed, valid := wavParseOrigination(string(dateMatch[1]), string(timeMatch[1])); valid {
+					debug("WAV iXML origination: %v", parsed)
+					candidates = append(candidates, Candidate{SourceOriginal, "iXML BWF_ORIGINATION_DATE", parsed, true})
+				}
+			}
+		}
+		// chunks are padded to even length:
+		offset += 8 + int64(chunkSize) + int64(chunkSize&1)
+	}
+
+	return candidates
+}
+
+// wavParseOrigination parses date and time written as 'yyyy-mm-dd' and 'hh:mm:ss',
+// specification allows any of '-', '_', ':', ' ', '.' as separators.
+func wavParseOrigination(date string, clock string) (time.Time, bool) {
+	var digits = func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}
+	var value = strings.Map(digits, date) + strings.Map(digits, clock)
+	parsed, err := time.Parse("20060102150405", value)
+	if err != nil {
+		debug("WAV failed to parse origination '%s %s': %v", date, clock, err)
+		return time.Time{}, false
+	}
+	return parsed, true
+}
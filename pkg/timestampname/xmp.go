@@ -0,0 +1,95 @@
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timestampname
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// following resources were used to implement this parser:
+// https://www.adobe.io/open/standards/XMP.html
+// https://github.com/adobe/xmp-docs/tree/master/XMPSpecifications
+
+var (
+	// properties in order of preference:
+	xmpProperties = []string{"exif:DateTimeOriginal", "xmp:CreateDate", "photoshop:DateCreated"}
+	// XMP dates are subset of ISO 8601, time zone and fractions are ignored
+	// to treat the value as local time, same as Exif:
+	xmpDatePattern = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})(?:T(\d{2}):(\d{2})(?::(\d{2}))?)?`)
+)
+
+// xmpParsePacket returns the preferred date from XMP packet,
+// or no candidates if packet has none of the known date properties.
+func xmpParsePacket(packet []byte, origin string) []Candidate {
+	for _, property := range xmpProperties {
+		var quoted = regexp.QuoteMeta(property)
+		// property may be serialized either as attribute or as element:
+		var pattern = regexp.MustCompile(quoted + `\s*=\s*["']([^"']*)["']|<` + quoted + `>\s*([^<]*?)\s*</` + quoted + `>`)
+		var match = pattern.FindSubmatch(packet)
+		if match == nil {
+			continue
+		}
+		var value = string(match[1]) + string(match[2])
+		debug("XMP property '%s' value: %s", property, value)
+		var dateMatch = xmpDatePattern.FindStringSubmatch(value)
+		if dateMatch == nil {
+			debug("XMP failed to parse property '%s' value: %s", property, value)
+			continue
+		}
+		var clock = dateMatch[4] + dateMatch[5] + dateMatch[6]
+		clock += strings.Repeat("0", 6-len(clock))
+		parsed, err := time.Parse("20060102150405", dateMatch[1]+dateMatch[2]+dateMatch[3]+clock)
+		if err != nil {
+			debug("XMP failed to parse property '%s' value: %s, %v", property, value, err)
+			continue
+		}
+		return []Candidate{{SourceXmp, origin + " " + property, parsed, true}}
+	}
+	return nil
+}
+
+// xmpSearchSidecar returns content of the sidecar file, both 'IMG_0001.xmp'
+// and 'IMG_0001.JPG.xmp' naming conventions are supported.
+func xmpSearchSidecar(path string) []byte {
+	var base = strings.TrimSuffix(path, filepath.Ext(path))
+	for _, candidate := range []string{base + ".xmp", base + ".XMP", path + ".xmp", path + ".XMP"} {
+		packet, err := os.ReadFile(candidate)
+		if err == nil {
+			debug("XMP sidecar found: %s", candidate)
+			return packet
+		}
+	}
+	return nil
+}
+
+// xmpExtractTimestampCandidates looks for XMP date embedded in the file,
+// only built-in extractors know where XMP is embedded.
+func xmpExtractTimestampCandidates(in reader, extractor Extractor) []Candidate {
+	var builtin, isBuiltin = extractor.(*builtinExtractor)
+	if !isBuiltin || builtin.searchXmp == nil {
+		return nil
+	}
+	var candidates []Candidate
+	err := try(func() {
+		if packet := builtin.searchXmp(in); packet != nil {
+			candidates = xmpParsePacket(packet, "embedded")
+		}
+	})
+	if err != nil {
+		debug("XMP failed to search embedded packet: %v", err)
+	}
+	return candidates
+}
+
+// xmpExtractSidecarTimestampCandidates looks for XMP date in the sidecar of the file.
+func xmpExtractSidecarTimestampCandidates(path string) []Candidate {
+	if packet := xmpSearchSidecar(path); packet != nil {
+		return xmpParsePacket(packet, "sidecar")
+	}
+	return nil
+}